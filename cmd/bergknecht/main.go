@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Nerdbergev/Bergknecht/pkg/bergknecht"
 	"github.com/Nerdbergev/Bergknecht/pkg/config"
@@ -22,7 +26,10 @@ func main() {
 		log.Fatal("Error loading config:", err)
 	}
 
-	err = bergknecht.RunBot(c)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = bergknecht.RunBot(ctx, c)
 	if err != nil {
 		log.Fatal("Error Running Bot:", err)
 	}
@@ -0,0 +1,28 @@
+package bergcrypto
+
+import (
+	"errors"
+
+	"maunium.net/go/mautrix/id"
+)
+
+const storageName = "Bergknecht"
+const cryptoStoreFile = "crypto.gob"
+
+type Config struct {
+	Enabled           bool
+	SendKeysMinTrust  string //Minimum trust of a device to send room keys to, e.g. "unset" or "cross-signed-tofu"
+	ShareKeysMinTrust string //Minimum trust of an own device to answer key requests from
+	AllowKeySharing   bool   //Answer room key requests of our own devices
+}
+
+func parseTrust(value string, fallback id.TrustState) (id.TrustState, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	ts := id.ParseTrustState(value)
+	if ts == id.TrustStateInvalid {
+		return fallback, errors.New("invalid trust state " + value)
+	}
+	return ts, nil
+}
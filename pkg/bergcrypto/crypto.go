@@ -0,0 +1,168 @@
+//go:build e2ee
+
+package bergcrypto
+
+import (
+	"errors"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/crypto"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+type Helper struct {
+	client *mautrix.Client
+	mach   *crypto.OlmMachine
+	state  *stateStore
+	logger *zap.SugaredLogger
+}
+
+type cryptoLogger struct {
+	sugar *zap.SugaredLogger
+}
+
+func (l cryptoLogger) Error(message string, args ...interface{}) {
+	l.sugar.Errorf(message, args...)
+}
+
+func (l cryptoLogger) Warn(message string, args ...interface{}) {
+	l.sugar.Warnf(message, args...)
+}
+
+func (l cryptoLogger) Debug(message string, args ...interface{}) {
+	l.sugar.Debugf(message, args...)
+}
+
+func (l cryptoLogger) Trace(message string, args ...interface{}) {}
+
+func NewHelper(c Config, client *mautrix.Client, sm *storage.Manager, sugar *zap.SugaredLogger) (*Helper, error) {
+	if client.DeviceID == "" {
+		return nil, errors.New("e2ee needs a device ID")
+	}
+	sendTrust, err := parseTrust(c.SendKeysMinTrust, id.TrustStateUnset)
+	if err != nil {
+		return nil, errors.New("Error parsing SendKeysMinTrust: " + err.Error())
+	}
+	shareTrust, err := parseTrust(c.ShareKeysMinTrust, id.TrustStateCrossSignedTOFU)
+	if err != nil {
+		return nil, errors.New("Error parsing ShareKeysMinTrust: " + err.Error())
+	}
+
+	path, err := sm.GetPath(storageName, cryptoStoreFile, true)
+	if err != nil {
+		return nil, errors.New("Error creating crypto store path: " + err.Error())
+	}
+	store, err := crypto.NewGobStore(path)
+	if err != nil {
+		return nil, errors.New("Error loading crypto store: " + err.Error())
+	}
+
+	h := new(Helper)
+	h.client = client
+	h.logger = sugar
+	h.state = newStateStore()
+	h.mach = crypto.NewOlmMachine(client, cryptoLogger{sugar}, store, h.state)
+	h.mach.SendKeysMinTrust = sendTrust
+	h.mach.ShareKeysMinTrust = shareTrust
+	if !c.AllowKeySharing {
+		h.mach.AllowKeyShare = func(*id.Device, event.RequestedKeyInfo) *crypto.KeyShareRejection {
+			return &crypto.KeyShareRejectNoResponse
+		}
+	}
+
+	err = h.loadRoomState()
+	if err != nil {
+		return nil, errors.New("Error loading room state: " + err.Error())
+	}
+
+	err = h.mach.Load()
+	if err != nil {
+		return nil, errors.New("Error loading olm account: " + err.Error())
+	}
+	err = h.mach.ShareKeys(-1)
+	if err != nil {
+		return nil, errors.New("Error uploading device keys: " + err.Error())
+	}
+	return h, nil
+}
+
+// loadRoomState fetches encryption and members of all joined rooms, a resumed
+// sync does not deliver the room state again.
+func (h *Helper) loadRoomState() error {
+	rooms, err := h.client.JoinedRooms()
+	if err != nil {
+		return err
+	}
+	for _, roomID := range rooms.JoinedRooms {
+		var encryption *event.EncryptionEventContent
+		var content event.EncryptionEventContent
+		err = h.client.StateEvent(roomID, event.StateEncryption, "", &content)
+		if err == nil {
+			encryption = &content
+		} else if !errors.Is(err, mautrix.MNotFound) {
+			return errors.New("Error fetching encryption state of " + roomID.String() + ": " + err.Error())
+		}
+		members, err := h.client.JoinedMembers(roomID)
+		if err != nil {
+			return errors.New("Error fetching members of " + roomID.String() + ": " + err.Error())
+		}
+		var users []id.UserID
+		for u := range members.Joined {
+			users = append(users, u)
+		}
+		h.state.setRoom(roomID, encryption, users)
+	}
+	return nil
+}
+
+// Register hooks the olm machine into the syncer, it has to be called before
+// the first sync so that the state store sees the initial room state.
+func (h *Helper) Register(syncer *mautrix.DefaultSyncer) {
+	syncer.OnSync(h.mach.ProcessSyncResponse)
+	syncer.OnEventType(event.StateEncryption, func(source mautrix.EventSource, evt *event.Event) {
+		h.state.updateState(evt)
+	})
+	syncer.OnEventType(event.StateMember, func(source mautrix.EventSource, evt *event.Event) {
+		h.state.updateState(evt)
+		h.mach.HandleMemberEvent(evt)
+	})
+}
+
+func (h *Helper) IsEncrypted(roomID id.RoomID) bool {
+	return h.state.IsEncrypted(roomID)
+}
+
+func (h *Helper) Encrypt(roomID id.RoomID, evtType event.Type, content interface{}) (*event.EncryptedEventContent, error) {
+	enc, err := h.mach.EncryptMegolmEvent(roomID, evtType, content)
+	if err == nil {
+		return enc, nil
+	}
+	if !crypto.IsShareError(err) {
+		return nil, err
+	}
+	h.logger.Debugw("Sharing group session", "room", roomID)
+	members, err := h.client.JoinedMembers(roomID)
+	if err != nil {
+		return nil, errors.New("Error fetching room members: " + err.Error())
+	}
+	var users []id.UserID
+	for u := range members.Joined {
+		users = append(users, u)
+	}
+	err = h.mach.ShareGroupSession(roomID, users)
+	if err != nil {
+		return nil, errors.New("Error sharing group session: " + err.Error())
+	}
+	return h.mach.EncryptMegolmEvent(roomID, evtType, content)
+}
+
+func (h *Helper) Decrypt(evt *event.Event) (*event.Event, error) {
+	return h.mach.DecryptMegolmEvent(evt)
+}
+
+func (h *Helper) Close() error {
+	return h.mach.FlushStore()
+}
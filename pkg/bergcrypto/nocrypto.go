@@ -0,0 +1,39 @@
+//go:build !e2ee
+
+package bergcrypto
+
+import (
+	"errors"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+var errNoCrypto = errors.New("Bergknecht was built without e2ee support, rebuild with -tags e2ee")
+
+type Helper struct{}
+
+func NewHelper(c Config, client *mautrix.Client, sm *storage.Manager, sugar *zap.SugaredLogger) (*Helper, error) {
+	return nil, errNoCrypto
+}
+
+func (h *Helper) Register(syncer *mautrix.DefaultSyncer) {}
+
+func (h *Helper) IsEncrypted(roomID id.RoomID) bool {
+	return false
+}
+
+func (h *Helper) Encrypt(roomID id.RoomID, evtType event.Type, content interface{}) (*event.EncryptedEventContent, error) {
+	return nil, errNoCrypto
+}
+
+func (h *Helper) Decrypt(evt *event.Event) (*event.Event, error) {
+	return nil, errNoCrypto
+}
+
+func (h *Helper) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package bergcrypto
+
+import (
+	"sync"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+type stateStore struct {
+	mu         sync.RWMutex
+	encryption map[id.RoomID]*event.EncryptionEventContent
+	members    map[id.RoomID]map[id.UserID]event.Membership
+}
+
+func newStateStore() *stateStore {
+	res := new(stateStore)
+	res.encryption = make(map[id.RoomID]*event.EncryptionEventContent)
+	res.members = make(map[id.RoomID]map[id.UserID]event.Membership)
+	return res
+}
+
+func (s *stateStore) updateState(evt *event.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch evt.Type {
+	case event.StateEncryption:
+		content := evt.Content.AsEncryption()
+		if content != nil {
+			s.encryption[evt.RoomID] = content
+		}
+	case event.StateMember:
+		content := evt.Content.AsMember()
+		if content == nil || evt.StateKey == nil {
+			return
+		}
+		rm, ex := s.members[evt.RoomID]
+		if !ex {
+			rm = make(map[id.UserID]event.Membership)
+			s.members[evt.RoomID] = rm
+		}
+		rm[id.UserID(*evt.StateKey)] = content.Membership
+	}
+}
+
+func (s *stateStore) setRoom(roomID id.RoomID, encryption *event.EncryptionEventContent, members []id.UserID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if encryption != nil {
+		s.encryption[roomID] = encryption
+	}
+	rm := make(map[id.UserID]event.Membership)
+	for _, m := range members {
+		rm[m] = event.MembershipJoin
+	}
+	s.members[roomID] = rm
+}
+
+func (s *stateStore) IsEncrypted(roomID id.RoomID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ex := s.encryption[roomID]
+	return ex
+}
+
+func (s *stateStore) GetEncryptionEvent(roomID id.RoomID) *event.EncryptionEventContent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.encryption[roomID]
+}
+
+func (s *stateStore) FindSharedRooms(userID id.UserID) []id.RoomID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []id.RoomID
+	for roomID, rm := range s.members {
+		if _, encrypted := s.encryption[roomID]; !encrypted {
+			continue
+		}
+		m := rm[userID]
+		if m == event.MembershipJoin || m == event.MembershipInvite {
+			result = append(result, roomID)
+		}
+	}
+	return result
+}
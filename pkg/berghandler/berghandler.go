@@ -1,26 +1,111 @@
 package berghandler
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
-	"fmt"
 	"strings"
 
 	"github.com/Nerdbergev/Bergknecht/pkg/storage"
 	"go.uber.org/zap"
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
 )
 
-var CommandPrefix = "!"
+const DefaultCommandPrefix = "!"
 
-const WrongArguments = "Falsche Anzahl an Argumenten, benutze %v help für Hilfe."
-const unkownCommand = "Unbekanntes Kommando, benutze %v help für Hilfe."
+// WrongArguments is the message key for a wrong argument count
+const WrongArguments = "args.wrongcount"
+
+// MatrixClient is the part of *mautrix.Client handlers may use, tests can
+// replace it with the fake from the bergtest package.
+type MatrixClient interface {
+	SendText(roomID id.RoomID, text string) (*mautrix.RespSendEvent, error)
+	SendMessageEvent(roomID id.RoomID, eventType event.Type, contentJSON interface{}, extra ...mautrix.ReqSendEvent) (*mautrix.RespSendEvent, error)
+	SendStateEvent(roomID id.RoomID, eventType event.Type, stateKey string, contentJSON interface{}) (*mautrix.RespSendEvent, error)
+	SendReaction(roomID id.RoomID, eventID id.EventID, reaction string) (*mautrix.RespSendEvent, error)
+	RedactEvent(roomID id.RoomID, eventID id.EventID, extra ...mautrix.ReqRedact) (*mautrix.RespSendEvent, error)
+	StateEvent(roomID id.RoomID, eventType event.Type, stateKey string, outContent interface{}) error
+	JoinRoom(roomIDorAlias, serverName string, content interface{}) (*mautrix.RespJoinRoom, error)
+	LeaveRoom(roomID id.RoomID, optionalReq ...*mautrix.ReqLeave) (*mautrix.RespLeaveRoom, error)
+}
 
 type HandlerEssentials struct {
-	Client  *mautrix.Client
-	Logger  *zap.SugaredLogger
-	Storage *storage.Manager
+	Client       MatrixClient
+	Logger       *zap.SugaredLogger
+	Storage      *storage.Manager
+	Crypto       Encrypter //nil if e2ee is disabled
+	Rooms        RoomManager
+	Permissions  PermissionConfig
+	Room         RoomSettings       //Settings of the room the current event is from
+	Active       []BergEventHandler //Handlers active in the room of the current event
+	Languages    LanguageManager
+	UserLanguage string          //Language chosen by the sender of the current event, overrides the room
+	Response     ResponseConfig  //How the current handler answers
+	Ctx          context.Context //Deadline of the current event, nil outside of the dispatcher
+}
+
+type RoomSettings struct {
+	Prefix            string
+	Handlers          []string //Handler names enabled in the room, empty for all
+	Language          string
+	DefaultRestaurant string
+}
+
+// Merge returns the settings with every field that is set in override replaced
+func (rs RoomSettings) Merge(override RoomSettings) RoomSettings {
+	if override.Prefix != "" {
+		rs.Prefix = override.Prefix
+	}
+	if len(override.Handlers) != 0 {
+		rs.Handlers = override.Handlers
+	}
+	if override.Language != "" {
+		rs.Language = override.Language
+	}
+	if override.DefaultRestaurant != "" {
+		rs.DefaultRestaurant = override.DefaultRestaurant
+	}
+	return rs
+}
+
+func (rs RoomSettings) IsHandlerEnabled(name string) bool {
+	if len(rs.Handlers) == 0 {
+		return true
+	}
+	for _, h := range rs.Handlers {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Context returns the context of the current event, handlers hand it to
+// everything that may block so they give up once the event timed out
+func (he HandlerEssentials) Context() context.Context {
+	if he.Ctx == nil {
+		return context.Background()
+	}
+	return he.Ctx
+}
+
+func (he HandlerEssentials) CommandPrefix() string {
+	if he.Room.Prefix == "" {
+		return DefaultCommandPrefix
+	}
+	return he.Room.Prefix
+}
+
+type RoomManager interface {
+	JoinRoom(roomIDorAlias string) (id.RoomID, error)
+	LeaveRoom(roomID id.RoomID) error
+	JoinedRooms() []id.RoomID
+}
+
+type Encrypter interface {
+	IsEncrypted(roomID id.RoomID) bool
+	Encrypt(roomID id.RoomID, evtType event.Type, content interface{}) (*event.EncryptedEventContent, error)
 }
 
 type BergEventHandler interface {
@@ -30,41 +115,59 @@ type BergEventHandler interface {
 	Prime(he HandlerEssentials) error
 }
 
+// BergShutdownHandler is implemented by handlers that need to flush state
+// before the bot exits.
+type BergShutdownHandler interface {
+	Shutdown(he HandlerEssentials) error
+}
+
 type BergEventHandleFunction func(he HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool
 
+// BergEventOwnerFunction reports whether the sender owns what the command
+// acts on, e.g. the order to close
+type BergEventOwnerFunction func(he HandlerEssentials, evt *event.Event, args Args) bool
+
 type SubHandlerSet struct {
 	F  BergEventHandleFunction //Function
-	H  string                  //Helptext
-	U  string                  //USagetext
+	A  BergEventArgsFunction   //Function getting parsed Args, used instead of F
+	P  []Param                 //Parameters for A
+	R  Role                    //Role needed to run the command
+	O  BergEventOwnerFunction  //Owners may run the command without role R, only with A
+	H  string                  //Helptext or message key
+	C  string                  //Category in the help, message key
+	E  []string                //Examples, arguments only
+	U  string                  //USagetext, generated from P if empty
 	NV int                     //Needed Variable Count
 	OV int                     //Optional Variable Count
 }
 
-type SubHandlers map[string]SubHandlerSet
+func (set SubHandlerSet) isValid() bool {
+	return set.F != nil || set.A != nil
+}
 
-func (s *SubHandlers) getAvailableCommands() string {
-	ss := *s
-	result := ""
-	for k := range ss {
-		result += (k + " ")
+func (set SubHandlerSet) usage(sub string) string {
+	if set.U != "" {
+		return set.U
 	}
-	return result
+	u := generateUsage(set.P)
+	if u == "" {
+		return sub
+	}
+	return sub + " " + u
 }
 
-func formatUsage(set SubHandlerSet, cmd string, help bool) string {
+type SubHandlers map[string]SubHandlerSet
 
-	if help {
-		return set.H + "\nUsage: !" + cmd + " " + set.U
-	}
-	return "\nUsage: !" + cmd + " " + set.U
+func formatUsage(he HandlerEssentials, set SubHandlerSet, cmd, sub string) string {
+	return "\n" + T(he, "help.usage") + ": " + he.CommandPrefix() + cmd + " " + set.usage(sub)
 }
 
 func (s *SubHandlers) Handle(command string, handlerName string, he HandlerEssentials, evt *event.Event) bool {
-	if IsMessagewithPrefix(evt, command) {
+	if IsMessagewithPrefix(he, evt, command) {
 		m := evt.Content.AsMessage()
-		words, err := StripPrefixandGetContent(m.Body, command)
+		words, err := StripPrefixandGetContent(he, ResolveMentions(m), command)
 		if err != nil {
-			return SendMessage(he, evt, handlerName, "Fehler bei decodieren der Nachricht: "+err.Error())
+			return SendFailure(he, evt, handlerName, Tf(he, "command.decode", TError(he, err)))
 		}
 		cmd := strings.ToLower(words[0])
 		newwords := RemoveWord(words, 0)
@@ -73,55 +176,79 @@ func (s *SubHandlers) Handle(command string, handlerName string, he HandlerEssen
 
 		if strings.Compare(cmd, "help") == 0 {
 			if len(newwords) == 0 {
-				msg := "Verfügbare Kommandos sind: \n"
-				msg += s.getAvailableCommands()
-				return SendMessage(he, evt, handlerName, msg)
+				return SendFormattedMessage(he, evt, handlerName, ss.renderHelp(he, command))
 			}
-			set := ss[newwords[0]]
-			f := set.F
-			if f != nil {
-				return SendMessage(he, evt, handlerName, formatUsage(set, command, true))
+			sub := strings.ToLower(newwords[0])
+			set := ss[sub]
+			if !set.isValid() {
+				msg := Paragraph(Tf(he, "help.unknown", sub) + DidYouMean(he, sub, ss.Names()))
+				return SendFormattedMessage(he, evt, handlerName, JoinFormatted(msg, ss.renderHelp(he, command)))
 			}
+			return SendMarkdown(he, evt, handlerName, formatDetails(he, set, command, sub))
 		}
 		set := ss[cmd]
-		f := set.F
-		if f == nil {
-			return SendMessage(he, evt, handlerName, fmt.Sprintf(unkownCommand, CommandPrefix+command))
+		if !set.isValid() {
+			return SendFailure(he, evt, handlerName, Tf(he, "command.unknown", he.CommandPrefix()+command)+DidYouMean(he, cmd, ss.Names()))
+		}
+		authorized := HasRole(he, evt, set.R)
+		if !authorized && (set.O == nil || set.A == nil) {
+			return SendFailure(he, evt, handlerName, Tf(he, "command.unauthorized", T(he, "role."+set.R.String())))
+		}
+		if set.A != nil {
+			args, err := ParseArgs(set.P, newwords)
+			if err != nil {
+				return SendFailure(he, evt, handlerName, Tf(he, "args.invalid", TError(he, err))+formatUsage(he, set, command, cmd))
+			}
+			if !authorized && !set.O(he, evt, args) {
+				return SendFailure(he, evt, handlerName, Tf(he, "command.notowner", T(he, "role."+set.R.String())))
+			}
+			return set.A(he, evt, args)
 		}
 		if len(newwords) < set.NV {
-			return SendMessage(he, evt, handlerName, "Too Few required variables. "+formatUsage(set, command, false))
+			return SendFailure(he, evt, handlerName, T(he, "args.toofew")+formatUsage(he, set, command, cmd))
 		}
-		return f(he, evt, newwords, set.NV, set.OV)
+		return set.F(he, evt, newwords, set.NV, set.OV)
 	}
 	return false
 }
 
-func SetCommandPrefix(prefix string) {
-	CommandPrefix = prefix
-}
-
-func IsMessagewithPrefix(evt *event.Event, prefix string) bool {
+func IsMessagewithPrefix(he HandlerEssentials, evt *event.Event, prefix string) bool {
 	result := false
 	if evt.Type == event.EventMessage {
 		m := evt.Content.AsMessage()
-		result = strings.HasPrefix(strings.ToLower(m.Body), CommandPrefix+prefix)
+		result = strings.HasPrefix(strings.ToLower(m.Body), he.CommandPrefix()+prefix)
 	}
 	return result
 }
 
-func StripPrefix(message, prefix string) string {
-	return strings.TrimPrefix(message, CommandPrefix+prefix+" ")
+func StripPrefix(he HandlerEssentials, message, prefix string) string {
+	return strings.TrimPrefix(message, he.CommandPrefix()+prefix+" ")
 }
 
-func StripPrefixandGetContent(message, prefix string) ([]string, error) {
-	message = StripPrefix(message, prefix)
-	r := csv.NewReader(strings.NewReader(message))
-	r.Comma = ' '
-	return r.Read()
+func StripPrefixandGetContent(he HandlerEssentials, message, prefix string) ([]string, error) {
+	message = StripPrefix(he, message, prefix)
+	words, err := SplitWords(message)
+	if err != nil {
+		return words, err
+	}
+	if len(words) == 0 {
+		return words, NewMessageError("args.empty")
+	}
+	return words, nil
 }
 
+func sendMessageEvent(he HandlerEssentials, evt *event.Event, content *event.MessageEventContent) (*mautrix.RespSendEvent, error) {
+	content.RelatesTo = responseRelation(he, evt)
+	return sendEvent(he, evt.RoomID, event.EventMessage, content)
+}
+
+// SendMessage reports false if msg could not be sent, with the outbound
+// queue that is only after retrying transient failures
 func SendMessage(he HandlerEssentials, evt *event.Event, handlerName, msg string) bool {
-	_, err := he.Client.SendText(evt.RoomID, msg)
+	_, err := sendMessageEvent(he, evt, &event.MessageEventContent{
+		MsgType: event.MsgText,
+		Body:    msg,
+	})
 	if err != nil {
 		he.Logger.Errorw("Error sending Message", "Handler", handlerName, "Error", err)
 		return false
@@ -129,12 +256,8 @@ func SendMessage(he HandlerEssentials, evt *event.Event, handlerName, msg string
 	return true
 }
 
-func SendFormattedMessage(he HandlerEssentials, evt *event.Event, handlerName, msg string) bool {
-	_, err := he.Client.SendMessageEvent(evt.RoomID, event.EventMessage, &event.MessageEventContent{
-		MsgType:       event.MsgText,
-		Format:        event.FormatHTML,
-		FormattedBody: msg,
-	})
+func SendFormattedMessage(he HandlerEssentials, evt *event.Event, handlerName string, msg Formatted) bool {
+	_, err := sendMessageEvent(he, evt, msg.content())
 	if err != nil {
 		he.Logger.Errorw("Error sending Message", "Handler", handlerName, "Error", err)
 		return false
@@ -142,6 +265,21 @@ func SendFormattedMessage(he HandlerEssentials, evt *event.Event, handlerName, m
 	return true
 }
 
+// SendToRoom sends msg to roomID without an event to answer, e.g. for
+// webhooks. Unlike the other send helpers it returns the error.
+func SendToRoom(he HandlerEssentials, roomID id.RoomID, handlerName string, msg Formatted) error {
+	_, err := sendEvent(he, roomID, event.EventMessage, msg.content())
+	if err != nil {
+		he.Logger.Errorw("Error sending Message", "Handler", handlerName, "Room", roomID, "Error", err)
+	}
+	return err
+}
+
+// SendMarkdown renders md and sends it formatted
+func SendMarkdown(he HandlerEssentials, evt *event.Event, handlerName, md string) bool {
+	return SendFormattedMessage(he, evt, handlerName, Markdown(md))
+}
+
 func SplitAnswer(words []string, RequiredCount, OptionalCount int, vars ...*string) error {
 	TotalCount := RequiredCount + OptionalCount
 	if len(vars) < TotalCount {
@@ -0,0 +1,192 @@
+// Package bergtest contains an in-memory Matrix client and helpers to drive
+// handlers with synthetic events, no homeserver needed.
+package bergtest
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+type SentEvent struct {
+	RoomID   id.RoomID
+	EventID  id.EventID
+	Type     event.Type
+	StateKey *string
+	Redacts  id.EventID
+	Content  interface{}
+}
+
+// Message returns the content as message content, nil for other event types
+func (se SentEvent) Message() *event.MessageEventContent {
+	if se.Type != event.EventMessage {
+		return nil
+	}
+	switch c := se.Content.(type) {
+	case *event.MessageEventContent:
+		return c
+	case event.MessageEventContent:
+		return &c
+	}
+	data, err := json.Marshal(se.Content)
+	if err != nil {
+		return nil
+	}
+	var res event.MessageEventContent
+	if json.Unmarshal(data, &res) != nil {
+		return nil
+	}
+	return &res
+}
+
+// FakeClient records everything that gets sent. Set Err to make all
+// requests fail.
+type FakeClient struct {
+	mu     sync.Mutex
+	nextID int
+	Sent   []SentEvent
+	Joined []string
+	Left   []id.RoomID
+	State  map[id.RoomID]map[string]interface{}
+	Err    error
+}
+
+func NewFakeClient() *FakeClient {
+	res := new(FakeClient)
+	res.State = make(map[id.RoomID]map[string]interface{})
+	return res
+}
+
+func (c *FakeClient) record(roomID id.RoomID, eventType event.Type, stateKey *string, content interface{}) (*mautrix.RespSendEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	c.nextID++
+	evtID := id.EventID("$fake" + strconv.Itoa(c.nextID))
+	c.Sent = append(c.Sent, SentEvent{RoomID: roomID, EventID: evtID, Type: eventType, StateKey: stateKey, Content: content})
+	return &mautrix.RespSendEvent{EventID: evtID}, nil
+}
+
+func (c *FakeClient) SendText(roomID id.RoomID, text string) (*mautrix.RespSendEvent, error) {
+	return c.record(roomID, event.EventMessage, nil, &event.MessageEventContent{MsgType: event.MsgText, Body: text})
+}
+
+func (c *FakeClient) SendMessageEvent(roomID id.RoomID, eventType event.Type, contentJSON interface{}, extra ...mautrix.ReqSendEvent) (*mautrix.RespSendEvent, error) {
+	return c.record(roomID, eventType, nil, contentJSON)
+}
+
+func (c *FakeClient) SendStateEvent(roomID id.RoomID, eventType event.Type, stateKey string, contentJSON interface{}) (*mautrix.RespSendEvent, error) {
+	resp, err := c.record(roomID, eventType, &stateKey, contentJSON)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rs, ex := c.State[roomID]
+	if !ex {
+		rs = make(map[string]interface{})
+		c.State[roomID] = rs
+	}
+	rs[eventType.Type+"|"+stateKey] = contentJSON
+	return resp, nil
+}
+
+func (c *FakeClient) SendReaction(roomID id.RoomID, eventID id.EventID, reaction string) (*mautrix.RespSendEvent, error) {
+	return c.record(roomID, event.EventReaction, nil, &event.ReactionEventContent{
+		RelatesTo: event.RelatesTo{EventID: eventID, Type: event.RelAnnotation, Key: reaction},
+	})
+}
+
+func (c *FakeClient) RedactEvent(roomID id.RoomID, eventID id.EventID, extra ...mautrix.ReqRedact) (*mautrix.RespSendEvent, error) {
+	content := &event.RedactionEventContent{}
+	if len(extra) > 0 {
+		content.Reason = extra[0].Reason
+	}
+	resp, err := c.record(roomID, event.EventRedaction, nil, content)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sent[len(c.Sent)-1].Redacts = eventID
+	return resp, nil
+}
+
+// StateEvent returns state set with SendStateEvent or SetState, it fails
+// with M_NOT_FOUND like a homeserver for unknown state
+func (c *FakeClient) StateEvent(roomID id.RoomID, eventType event.Type, stateKey string, outContent interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return c.Err
+	}
+	content, ex := c.State[roomID][eventType.Type+"|"+stateKey]
+	if !ex {
+		return mautrix.HTTPError{RespError: &mautrix.RespError{ErrCode: mautrix.MNotFound.ErrCode, Err: "Event not found."}}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return errors.New("Error encoding state: " + err.Error())
+	}
+	return json.Unmarshal(data, outContent)
+}
+
+func (c *FakeClient) SetState(roomID id.RoomID, eventType event.Type, stateKey string, content interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rs, ex := c.State[roomID]
+	if !ex {
+		rs = make(map[string]interface{})
+		c.State[roomID] = rs
+	}
+	rs[eventType.Type+"|"+stateKey] = content
+}
+
+func (c *FakeClient) JoinRoom(roomIDorAlias, serverName string, content interface{}) (*mautrix.RespJoinRoom, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	c.Joined = append(c.Joined, roomIDorAlias)
+	return &mautrix.RespJoinRoom{RoomID: id.RoomID(roomIDorAlias)}, nil
+}
+
+func (c *FakeClient) LeaveRoom(roomID id.RoomID, optionalReq ...*mautrix.ReqLeave) (*mautrix.RespLeaveRoom, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	c.Left = append(c.Left, roomID)
+	return &mautrix.RespLeaveRoom{}, nil
+}
+
+// Messages returns all sent message events in order
+func (c *FakeClient) Messages() []*event.MessageEventContent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var result []*event.MessageEventContent
+	for _, se := range c.Sent {
+		m := se.Message()
+		if m != nil {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func (c *FakeClient) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sent = nil
+	c.Joined = nil
+	c.Left = nil
+}
@@ -0,0 +1,142 @@
+package bergtest
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const DefaultRoom = id.RoomID("!testroom:example.org")
+const DefaultSender = id.UserID("@tester:example.org")
+
+// FakeRooms is an in-memory berghandler.RoomManager
+type FakeRooms struct {
+	mu    sync.Mutex
+	rooms map[id.RoomID]bool
+}
+
+func NewFakeRooms(rooms ...id.RoomID) *FakeRooms {
+	res := new(FakeRooms)
+	res.rooms = make(map[id.RoomID]bool)
+	for _, r := range rooms {
+		res.rooms[r] = true
+	}
+	return res
+}
+
+func (fr *FakeRooms) JoinRoom(roomIDorAlias string) (id.RoomID, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.rooms[id.RoomID(roomIDorAlias)] = true
+	return id.RoomID(roomIDorAlias), nil
+}
+
+func (fr *FakeRooms) LeaveRoom(roomID id.RoomID) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	delete(fr.rooms, roomID)
+	return nil
+}
+
+func (fr *FakeRooms) JoinedRooms() []id.RoomID {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	var result []id.RoomID
+	for r := range fr.rooms {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// Harness feeds commands to a single handler and collects its replies.
+// Storage lives below the given directory, usually t.TempDir().
+type Harness struct {
+	Handler berghandler.BergEventHandler
+	Client  *FakeClient
+	HE      berghandler.HandlerEssentials
+	RoomID  id.RoomID
+	Sender  id.UserID
+	events  int
+}
+
+func NewHarness(h berghandler.BergEventHandler, dir string) *Harness {
+	res := new(Harness)
+	res.Handler = h
+	res.Client = NewFakeClient()
+	res.RoomID = DefaultRoom
+	res.Sender = DefaultSender
+	res.HE = berghandler.HandlerEssentials{
+		Client:  res.Client,
+		Logger:  zap.NewNop().Sugar(),
+		Storage: storage.CreateStorageManager(storage.Config{CachedPath: dir, PersistentPath: dir}),
+		Rooms:   NewFakeRooms(DefaultRoom),
+		Room:    berghandler.RoomSettings{Prefix: berghandler.DefaultCommandPrefix},
+	}
+	return res
+}
+
+// Prime primes the handler, persistent handler data has to be written to
+// HE.Storage before.
+func (h *Harness) Prime() error {
+	return h.Handler.Prime(h.HE)
+}
+
+// Event builds a text message event as it would arrive from the syncer
+func (h *Harness) Event(sender id.UserID, body string) *event.Event {
+	h.events++
+	content := &event.MessageEventContent{MsgType: event.MsgText, Body: body}
+	return &event.Event{
+		Type:      event.EventMessage,
+		ID:        id.EventID("$cmd" + strconv.Itoa(h.events)),
+		RoomID:    h.RoomID,
+		Sender:    sender,
+		Timestamp: time.Now().UnixMilli(),
+		Content:   event.Content{Parsed: content},
+	}
+}
+
+// Send delivers body from the default sender and returns whether the handler
+// took the event and the bodies of all messages sent while handling it,
+// edits are reported with their new content.
+func (h *Harness) Send(body string) (bool, []string) {
+	return h.SendAs(h.Sender, body)
+}
+
+func (h *Harness) SendAs(sender id.UserID, body string) (bool, []string) {
+	before := len(h.Client.Messages())
+	handled := h.Handler.Handle(h.HE, mautrix.EventSourceTimeline, h.Event(sender, body))
+	var replies []string
+	for _, m := range h.Client.Messages()[before:] {
+		if m.NewContent != nil {
+			m = m.NewContent
+		}
+		if m.FormattedBody != "" && m.Body == "" {
+			replies = append(replies, m.FormattedBody)
+		} else {
+			replies = append(replies, m.Body)
+		}
+	}
+	return handled, replies
+}
+
+// LastReply returns the body of the last sent message, empty if none
+func (h *Harness) LastReply() string {
+	msgs := h.Client.Messages()
+	if len(msgs) == 0 {
+		return ""
+	}
+	m := msgs[len(msgs)-1]
+	if m.Body == "" {
+		return m.FormattedBody
+	}
+	return m.Body
+}
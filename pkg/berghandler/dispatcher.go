@@ -0,0 +1,118 @@
+package berghandler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix/id"
+)
+
+const defaultWorkers = 4
+const defaultHandlerTimeout = 60 * time.Second
+
+type DispatchConfig struct {
+	Workers        int //Rooms processed in parallel
+	TimeoutSeconds int //A handler running longer gets its context cancelled, its room and worker go on without it
+}
+
+// Job handles one event, it should give up once ctx is done
+type Job func(ctx context.Context)
+
+// Dispatcher runs the jobs of one room in order and up to Workers rooms in
+// parallel, so a slow handler in one room does not stall the others.
+type Dispatcher struct {
+	mu      sync.Mutex
+	queues  map[id.RoomID][]Job
+	sem     chan struct{}
+	timeout time.Duration
+	logger  *zap.SugaredLogger
+	wg      sync.WaitGroup
+}
+
+func NewDispatcher(c DispatchConfig, logger *zap.SugaredLogger) *Dispatcher {
+	workers := c.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	res := new(Dispatcher)
+	res.queues = make(map[id.RoomID][]Job)
+	res.sem = make(chan struct{}, workers)
+	res.timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	if res.timeout <= 0 {
+		res.timeout = defaultHandlerTimeout
+	}
+	res.logger = logger
+	return res
+}
+
+func (d *Dispatcher) Dispatch(roomID id.RoomID, job Job) {
+	d.wg.Add(1)
+	d.mu.Lock()
+	q, running := d.queues[roomID]
+	d.queues[roomID] = append(q, job)
+	d.mu.Unlock()
+	if !running {
+		go d.runRoom(roomID)
+	}
+}
+
+// runRoom works through the queue of a room, the queue entry is removed once
+// it is empty so the next Dispatch starts a new runner.
+func (d *Dispatcher) runRoom(roomID id.RoomID) {
+	for {
+		d.mu.Lock()
+		q := d.queues[roomID]
+		if len(q) == 0 {
+			delete(d.queues, roomID)
+			d.mu.Unlock()
+			return
+		}
+		job := q[0]
+		d.queues[roomID] = q[1:]
+		d.mu.Unlock()
+
+		d.sem <- struct{}{}
+		d.run(roomID, job)
+		<-d.sem
+		d.wg.Done()
+	}
+}
+
+// run gives the job a context with the timeout as deadline, a job that does
+// not return in time is left behind so its room and the worker go on
+func (d *Dispatcher) run(roomID id.RoomID, job Job) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job(ctx)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.logger.Warnw("Handler timed out, releasing its room", "room", roomID, "timeout", d.timeout, "started", start)
+		go func() {
+			<-done
+			d.logger.Warnw("Handler returned after timeout", "room", roomID, "duration", time.Since(start))
+		}()
+	}
+}
+
+// Wait blocks until all queued jobs are done or the timeout is reached
+func (d *Dispatcher) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,107 @@
+package berghandler
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix/id"
+)
+
+func newTestDispatcher(workers int) *Dispatcher {
+	return NewDispatcher(DispatchConfig{Workers: workers}, zap.NewNop().Sugar())
+}
+
+func TestDispatcherRoomOrder(t *testing.T) {
+	d := newTestDispatcher(3)
+	var mu sync.Mutex
+	got := make(map[id.RoomID][]int)
+	rooms := []id.RoomID{"!a:test", "!b:test", "!c:test", "!d:test"}
+	for i := 0; i < 50; i++ {
+		for _, r := range rooms {
+			i, r := i, r
+			d.Dispatch(r, func(ctx context.Context) {
+				if i%7 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				mu.Lock()
+				got[r] = append(got[r], i)
+				mu.Unlock()
+			})
+		}
+	}
+	if !d.Wait(5 * time.Second) {
+		t.Fatal("jobs did not finish")
+	}
+	for _, r := range rooms {
+		if len(got[r]) != 50 {
+			t.Fatalf("room %v ran %v jobs, want 50", r, len(got[r]))
+		}
+		for i, v := range got[r] {
+			if v != i {
+				t.Fatalf("room %v ran job %v at position %v", r, v, i)
+			}
+		}
+	}
+}
+
+func TestDispatcherWorkerBound(t *testing.T) {
+	const workers = 2
+	d := newTestDispatcher(workers)
+	var active, max int32
+	for i := 0; i < 8; i++ {
+		d.Dispatch(id.RoomID("!room"+strconv.Itoa(i)+":test"), func(ctx context.Context) {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		})
+	}
+	if !d.Wait(5 * time.Second) {
+		t.Fatal("jobs did not finish")
+	}
+	if max > workers {
+		t.Errorf("%v jobs ran at the same time, want at most %v", max, workers)
+	}
+}
+
+func TestDispatcherTimeoutReleasesRoom(t *testing.T) {
+	d := newTestDispatcher(1)
+	d.timeout = 10 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	hungCtx := make(chan context.Context, 1)
+	var nextRan, otherRan int32
+	d.Dispatch("!a:test", func(ctx context.Context) {
+		hungCtx <- ctx
+		<-release
+	})
+	d.Dispatch("!a:test", func(ctx context.Context) {
+		atomic.StoreInt32(&nextRan, 1)
+	})
+	d.Dispatch("!b:test", func(ctx context.Context) {
+		atomic.StoreInt32(&otherRan, 1)
+	})
+
+	if !d.Wait(5 * time.Second) {
+		t.Fatal("a hung handler blocked the only worker")
+	}
+	if atomic.LoadInt32(&nextRan) != 1 {
+		t.Error("next job of the room did not run after the timeout")
+	}
+	if atomic.LoadInt32(&otherRan) != 1 {
+		t.Error("job of the other room did not run after the timeout")
+	}
+	if err := (<-hungCtx).Err(); err != context.DeadlineExceeded {
+		t.Errorf("context of the hung job = %v, want it cancelled by the deadline", err)
+	}
+}
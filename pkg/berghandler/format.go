@@ -0,0 +1,78 @@
+package berghandler
+
+import (
+	"html"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/format"
+)
+
+// Formatted is an HTML message together with the plain text Body for
+// clients, notifications and bridges that do not render HTML.
+type Formatted struct {
+	HTML  string
+	Plain string
+}
+
+// HTML wraps msg, the plain text is derived from it
+func HTML(msg string) Formatted {
+	return Formatted{HTML: msg, Plain: format.HTMLToText(msg)}
+}
+
+// Paragraph escapes text and wraps it in a paragraph
+func Paragraph(text string) Formatted {
+	return Formatted{HTML: "<p>" + html.EscapeString(text) + "</p>", Plain: text}
+}
+
+// Markdown renders md to HTML, raw HTML in md is escaped
+func Markdown(md string) Formatted {
+	content := format.RenderMarkdown(md, true, false)
+	if content.Format != event.FormatHTML {
+		return Paragraph(content.Body)
+	}
+	return Formatted{HTML: content.FormattedBody, Plain: content.Body}
+}
+
+// RenderTable renders t as HTML and as plain text table with the default
+// style, the colors of the HTML style would end up as escape codes.
+func RenderTable(t table.Writer) Formatted {
+	res := Formatted{HTML: t.RenderHTML()}
+	style := *t.Style()
+	t.SetStyle(table.StyleDefault)
+	res.Plain = t.Render()
+	t.SetStyle(style)
+	return res
+}
+
+// JoinFormatted concatenates the parts, the plain texts line by line
+func JoinFormatted(parts ...Formatted) Formatted {
+	var res Formatted
+	var plain []string
+	for _, p := range parts {
+		res.HTML += p.HTML
+		if p.Plain != "" {
+			plain = append(plain, p.Plain)
+		}
+	}
+	res.Plain = strings.Join(plain, "\n")
+	return res
+}
+
+// Text is a message without formatting
+func Text(text string) Formatted {
+	return Formatted{Plain: text}
+}
+
+func (f Formatted) content() *event.MessageEventContent {
+	if f.HTML == "" {
+		return &event.MessageEventContent{MsgType: event.MsgText, Body: f.Plain}
+	}
+	return &event.MessageEventContent{
+		MsgType:       event.MsgText,
+		Body:          f.Plain,
+		Format:        event.FormatHTML,
+		FormattedBody: f.HTML,
+	}
+}
@@ -0,0 +1,133 @@
+package berghandler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// BergHelpDescriber is implemented by handlers that want a description in
+// the global help, the description may be a message key.
+type BergHelpDescriber interface {
+	GetDescription() string
+}
+
+// Names returns the sub-commands sorted
+func (s SubHandlers) Names() []string {
+	var result []string
+	for k := range s {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Has reports whether sub is a sub-command, help always is one
+func (s SubHandlers) Has(sub string) bool {
+	if sub == "help" {
+		return true
+	}
+	_, ex := s[sub]
+	return ex
+}
+
+// categories groups the sub-commands by category, the uncategorized ones come
+// first, the others are sorted by their translated name
+func (s SubHandlers) categories(he HandlerEssentials) ([]string, map[string][]string) {
+	groups := make(map[string][]string)
+	var names []string
+	for _, sub := range s.Names() {
+		c := s[sub].C
+		if _, ex := groups[c]; !ex {
+			names = append(names, c)
+		}
+		groups[c] = append(groups[c], sub)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		if names[i] == "" || names[j] == "" {
+			return names[i] == ""
+		}
+		return T(he, names[i]) < T(he, names[j])
+	})
+	return names, groups
+}
+
+func (set SubHandlerSet) examples(he HandlerEssentials, cmd, sub string) []string {
+	var result []string
+	for _, e := range set.E {
+		result = append(result, he.CommandPrefix()+cmd+" "+sub+" "+e)
+	}
+	return result
+}
+
+// renderHelp renders one table per category with usage, description and
+// examples of every sub-command
+func (s SubHandlers) renderHelp(he HandlerEssentials, cmd string) Formatted {
+	names, groups := s.categories(he)
+	var result []Formatted
+	for _, c := range names {
+		title := T(he, "help.category.general")
+		if c != "" {
+			title = T(he, c)
+		}
+		t := table.NewWriter()
+		t.SetStyle(table.StyleColoredDark)
+		t.SetTitle(Tf(he, "help.title", he.CommandPrefix()+cmd) + " - " + title)
+		t.AppendHeader(table.Row{T(he, "help.usage"), T(he, "help.description"), T(he, "help.examples")})
+		for _, sub := range groups[c] {
+			set := s[sub]
+			t.AppendRow(table.Row{set.usage(sub), T(he, set.H), strings.Join(set.examples(he, cmd, sub), "\n")})
+		}
+		result = append(result, RenderTable(t))
+	}
+	result = append(result, Paragraph(Tf(he, "help.details", he.CommandPrefix()+cmd)))
+	return JoinFormatted(result...)
+}
+
+// formatDetails is the help of a single sub-command as markdown
+func formatDetails(he HandlerEssentials, set SubHandlerSet, cmd, sub string) string {
+	msg := T(he, set.H) + "\n**" + T(he, "help.usage") + ":** `" + he.CommandPrefix() + cmd + " " + set.usage(sub) + "`"
+	examples := set.examples(he, cmd, sub)
+	if len(examples) > 0 {
+		msg += "\n**" + T(he, "help.examples") + ":**"
+		for _, e := range examples {
+			msg += "\n`" + e + "`"
+		}
+	}
+	if set.R != RoleGuest {
+		msg += "\n**" + T(he, "help.role") + ":** " + T(he, "role."+set.R.String())
+		if set.O != nil {
+			msg += " " + T(he, "help.orowner")
+		}
+	}
+	if hasOptional(set.P) {
+		msg += "\n" + T(he, "help.named")
+	}
+	return msg
+}
+
+// RenderGlobalHelp lists the commands of the given handlers as table
+func RenderGlobalHelp(he HandlerEssentials, handlers []BergEventHandler) Formatted {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleColoredDark)
+	t.SetTitle(T(he, "help.global.title"))
+	t.AppendHeader(table.Row{T(he, "help.command"), T(he, "help.description")})
+	rows := 0
+	for _, h := range handlers {
+		if h.GetCommand() == "" {
+			continue
+		}
+		desc := ""
+		if d, ok := h.(BergHelpDescriber); ok {
+			desc = T(he, d.GetDescription())
+		}
+		t.AppendRow(table.Row{he.CommandPrefix() + h.GetCommand(), desc})
+		rows++
+	}
+	if rows == 0 {
+		return Paragraph(T(he, "help.none"))
+	}
+	t.SortBy([]table.SortBy{{Number: 1, Mode: table.Asc}})
+	return JoinFormatted(RenderTable(t), Paragraph(Tf(he, "help.global.details", he.CommandPrefix())))
+}
@@ -0,0 +1,99 @@
+package berghandler
+
+import (
+	"errors"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// LiveMessage is a posted message that gets edited in place with m.replace
+// instead of posting a new one on every change. Handlers store it together
+// with the data it shows.
+type LiveMessage struct {
+	RoomID  id.RoomID
+	EventID id.EventID
+}
+
+func (lm LiveMessage) IsSet() bool {
+	return lm.EventID != ""
+}
+
+// PostLiveMessage posts msg as new top-level message in roomID
+func PostLiveMessage(he HandlerEssentials, roomID id.RoomID, msg Formatted) (LiveMessage, error) {
+	resp, err := sendEvent(he, roomID, event.EventMessage, msg.content())
+	if err != nil {
+		return LiveMessage{}, errors.New("Error posting live message: " + err.Error())
+	}
+	return LiveMessage{RoomID: roomID, EventID: resp.EventID}, nil
+}
+
+// Update replaces the content of the message with msg
+func (lm LiveMessage) Update(he HandlerEssentials, msg Formatted) error {
+	content := msg.content()
+	content.SetEdit(lm.EventID)
+	_, err := sendEvent(he, lm.RoomID, event.EventMessage, content)
+	if err != nil {
+		return errors.New("Error updating live message: " + err.Error())
+	}
+	return nil
+}
+
+func pinnedEvents(he HandlerEssentials, roomID id.RoomID) ([]id.EventID, error) {
+	var content event.PinnedEventsEventContent
+	err := he.Context().Err()
+	if err != nil {
+		return nil, errors.New("Not changing pinned events, handler gave up: " + err.Error())
+	}
+	err = he.Client.StateEvent(roomID, event.StatePinnedEvents, "", &content)
+	if err != nil && !errors.Is(err, mautrix.MNotFound) {
+		return nil, errors.New("Error getting pinned events: " + err.Error())
+	}
+	return content.Pinned, nil
+}
+
+// Pin adds the message to the pinned events of its room, the bot needs the
+// power level for m.room.pinned_events.
+func (lm LiveMessage) Pin(he HandlerEssentials) error {
+	pinned, err := pinnedEvents(he, lm.RoomID)
+	if err != nil {
+		return err
+	}
+	for _, p := range pinned {
+		if p == lm.EventID {
+			return nil
+		}
+	}
+	pinned = append(pinned, lm.EventID)
+	_, err = he.Client.SendStateEvent(lm.RoomID, event.StatePinnedEvents, "", &event.PinnedEventsEventContent{Pinned: pinned})
+	if err != nil {
+		return errors.New("Error pinning message: " + err.Error())
+	}
+	return nil
+}
+
+// Unpin removes the message from the pinned events of its room
+func (lm LiveMessage) Unpin(he HandlerEssentials) error {
+	pinned, err := pinnedEvents(he, lm.RoomID)
+	if err != nil {
+		return err
+	}
+	var result []id.EventID
+	for _, p := range pinned {
+		if p != lm.EventID {
+			result = append(result, p)
+		}
+	}
+	if len(result) == len(pinned) {
+		return nil
+	}
+	if result == nil {
+		result = []id.EventID{}
+	}
+	_, err = he.Client.SendStateEvent(lm.RoomID, event.StatePinnedEvents, "", &event.PinnedEventsEventContent{Pinned: result})
+	if err != nil {
+		return errors.New("Error unpinning message: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package berghandler
+
+import (
+	"fmt"
+	"sort"
+
+	"maunium.net/go/mautrix/id"
+)
+
+const DefaultLanguage = "de"
+
+// Messages maps message keys to the text in one language
+type Messages map[string]string
+
+var catalogs = make(map[string]Messages)
+
+// LanguageManager stores the language users chose for the answers
+type LanguageManager interface {
+	UserLanguage(userID id.UserID) string
+	SetUserLanguage(userID id.UserID, lang string) error
+}
+
+// RegisterMessages adds messages to the catalog of a language. Like
+// RegisterHandler it is meant to be called from init functions.
+func RegisterMessages(lang string, msgs Messages) {
+	c, ex := catalogs[lang]
+	if !ex {
+		c = make(Messages)
+		catalogs[lang] = c
+	}
+	for k, v := range msgs {
+		c[k] = v
+	}
+}
+
+// Languages returns all languages with a catalog sorted
+func Languages() []string {
+	var result []string
+	for k := range catalogs {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func HasLanguage(lang string) bool {
+	_, ex := catalogs[lang]
+	return ex
+}
+
+// Language returns the language of the answers, the one of the sender if set,
+// otherwise the one of the room
+func (he HandlerEssentials) Language() string {
+	if he.UserLanguage != "" {
+		return he.UserLanguage
+	}
+	if he.Room.Language == "" {
+		return DefaultLanguage
+	}
+	return he.Room.Language
+}
+
+// TLang returns the message for key in lang, falling back to the default
+// language and then to key itself, so plain texts work as keys.
+func TLang(lang, key string) string {
+	if m, ex := catalogs[lang][key]; ex {
+		return m
+	}
+	if m, ex := catalogs[DefaultLanguage][key]; ex {
+		return m
+	}
+	return key
+}
+
+func T(he HandlerEssentials, key string) string {
+	return TLang(he.Language(), key)
+}
+
+func Tf(he HandlerEssentials, key string, a ...interface{}) string {
+	return fmt.Sprintf(T(he, key), a...)
+}
+
+// MessageError is an error meant for users, TError translates it, Error
+// uses the default language
+type MessageError struct {
+	Key  string
+	Args []interface{}
+}
+
+func NewMessageError(key string, a ...interface{}) MessageError {
+	return MessageError{Key: key, Args: a}
+}
+
+func (e MessageError) Error() string {
+	return fmt.Sprintf(TLang(DefaultLanguage, e.Key), e.Args...)
+}
+
+// TError returns the message of err in the language of he
+func TError(he HandlerEssentials, err error) string {
+	if me, ok := err.(MessageError); ok {
+		return Tf(he, me.Key, me.Args...)
+	}
+	return err.Error()
+}
+
+func init() {
+	RegisterMessages("de", Messages{
+		WrongArguments:          "Falsche Anzahl an Argumenten, benutze %v help für Hilfe.",
+		"command.unknown":       "Unbekanntes Kommando, benutze %v help für Hilfe.",
+		"command.decode":        "Fehler bei decodieren der Nachricht: %v",
+		"command.unauthorized":  "Keine Berechtigung, benötigt die Rolle %v.",
+		"command.notowner":      "Keine Berechtigung, nur für den Ersteller oder die Rolle %v.",
+		"handler.panic":         "Interner Fehler beim Ausführen des Kommandos, bitte melde das einem Admin.",
+		"ratelimit.throttled":   "Zu viele Kommandos, bitte warte %v Sekunden.",
+		"args.invalid":          "Ungültige Argumente: %v.",
+		"args.toofew":           "Zu wenige Argumente.",
+		"args.toomany":          "zu viele Argumente",
+		"args.missing":          "$%v fehlt",
+		"args.missingafter":     "$%v fehlt nach --%v",
+		"args.twice":            "$%v mehrfach angegeben",
+		"args.unknownoption":    "unbekannte Option --%v",
+		"args.int":              "$%v muss eine ganze Zahl sein",
+		"args.money":            "$%v muss ein Geldbetrag sein",
+		"args.user":             "$%v ist kein Matrix Benutzer (@name:server)",
+		"args.order":            "$%v ist kein Bestellungsname",
+		"args.bool":             "$%v muss ja oder nein sein",
+		"args.enum":             "$%v muss eins von %v sein",
+		"args.quote":            "Anführungszeichen nicht geschlossen",
+		"args.empty":            "keine Wörter gefunden",
+		"help.title":            "Hilfe für %v",
+		"help.global.title":     "Verfügbare Befehle",
+		"help.global.details":   "Details zu einem Befehl mit %v$Befehl help",
+		"help.command":          "Kommando",
+		"help.usage":            "Verwendung",
+		"help.description":      "Beschreibung",
+		"help.examples":         "Beispiele",
+		"help.role":             "Benötigte Rolle",
+		"help.orowner":          "oder Ersteller",
+		"help.category.general": "Allgemein",
+		"help.details":          "Details zu einem Kommando mit %v help $Kommando",
+		"help.unknown":          "Unbekanntes Kommando %v.",
+		"help.named":            "Optionale Argumente können auch benannt angegeben werden, z.B. name=wert oder --name wert.",
+		"help.none":             "Keine Befehle verfügbar.",
+		"suggest.didyoumean":    "Meintest du: %v?",
+		"role.admin":            "Admin",
+		"role.member":           "Mitglied",
+		"role.guest":            "Gast",
+	})
+	RegisterMessages("en", Messages{
+		WrongArguments:          "Wrong number of arguments, use %v help for help.",
+		"command.unknown":       "Unknown command, use %v help for help.",
+		"command.decode":        "Error decoding the message: %v",
+		"command.unauthorized":  "Not allowed, needs the role %v.",
+		"command.notowner":      "Not allowed, only for the owner or the role %v.",
+		"handler.panic":         "Internal error while running the command, please tell an admin.",
+		"ratelimit.throttled":   "Too many commands, please wait %v seconds.",
+		"args.invalid":          "Invalid arguments: %v.",
+		"args.toofew":           "Too few arguments.",
+		"args.toomany":          "too many arguments",
+		"args.missing":          "$%v is missing",
+		"args.missingafter":     "$%v is missing after --%v",
+		"args.twice":            "$%v given more than once",
+		"args.unknownoption":    "unknown option --%v",
+		"args.int":              "$%v has to be a whole number",
+		"args.money":            "$%v has to be an amount of money",
+		"args.user":             "$%v is no Matrix user (@name:server)",
+		"args.order":            "$%v is no order name",
+		"args.bool":             "$%v has to be yes or no",
+		"args.enum":             "$%v has to be one of %v",
+		"args.quote":            "quotation mark not closed",
+		"args.empty":            "no words found",
+		"help.title":            "Help for %v",
+		"help.global.title":     "Available commands",
+		"help.global.details":   "Details about a command with %v$command help",
+		"help.command":          "Command",
+		"help.usage":            "Usage",
+		"help.description":      "Description",
+		"help.examples":         "Examples",
+		"help.role":             "Required role",
+		"help.orowner":          "or owner",
+		"help.category.general": "General",
+		"help.details":          "Details about a sub-command with %v help $command",
+		"help.unknown":          "Unknown command %v.",
+		"help.named":            "Optional arguments can also be given by name, e.g. name=value or --name value.",
+		"help.none":             "No commands available.",
+		"suggest.didyoumean":    "Did you mean: %v?",
+		"role.admin":            "admin",
+		"role.member":           "member",
+		"role.guest":            "guest",
+	})
+}
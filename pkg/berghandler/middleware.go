@@ -0,0 +1,128 @@
+package berghandler
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+)
+
+// Invocation is a single call of a handler with an event
+type Invocation struct {
+	Handler BergEventHandler
+	Source  mautrix.EventSource
+	Event   *event.Event
+	Command string //Command of the handler if the event is addressed to it
+	Sub     string //Sub-command in lower case, empty if there is none
+	Start   time.Time
+}
+
+// IsCommand reports whether the event is a command for the handler
+func (inv *Invocation) IsCommand() bool {
+	return inv.Command != ""
+}
+
+// HandleFunc runs an invocation and reports whether the event was handled
+type HandleFunc func(he HandlerEssentials, inv *Invocation) bool
+
+// Middleware wraps the invocation of a handler, it may call next or not
+type Middleware func(next HandleFunc) HandleFunc
+
+// Chain of middlewares, the first one is the outermost
+type Chain []Middleware
+
+// DefaultChain logs every handled command and recovers from panics
+func DefaultChain() Chain {
+	return Chain{LogInvocation, Recover}
+}
+
+func newInvocation(he HandlerEssentials, h BergEventHandler, source mautrix.EventSource, evt *event.Event) *Invocation {
+	inv := &Invocation{Handler: h, Source: source, Event: evt, Start: time.Now()}
+	cmd := h.GetCommand()
+	if cmd == "" || evt.Type != event.EventMessage || !IsMessagewithPrefix(he, evt, cmd) {
+		return inv
+	}
+	inv.Command = cmd
+	words, err := StripPrefixandGetContent(he, evt.Content.AsMessage().Body, cmd)
+	if err == nil {
+		inv.Sub = strings.ToLower(words[0])
+	}
+	return inv
+}
+
+// Handle runs h with evt through the chain
+func (c Chain) Handle(he HandlerEssentials, h BergEventHandler, source mautrix.EventSource, evt *event.Event) bool {
+	next := func(he HandlerEssentials, inv *Invocation) bool {
+		return inv.Handler.Handle(he, inv.Source, inv.Event)
+	}
+	for i := len(c) - 1; i >= 0; i-- {
+		next = c[i](next)
+	}
+	return next(he, newInvocation(he, h, source, evt))
+}
+
+// Recover stops a panicking handler from taking down the bot, the event
+// counts as handled and the sender of a command gets an error message.
+func Recover(next HandleFunc) HandleFunc {
+	return func(he HandlerEssentials, inv *Invocation) (handled bool) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			he.Logger.Errorw("Handler panicked", "Handler", inv.Handler.GetName(), "Command", inv.Command, "Sub", inv.Sub,
+				"Room", inv.Event.RoomID, "Event", inv.Event.ID, "Panic", fmt.Sprint(r), "Stack", string(debug.Stack()))
+			if inv.IsCommand() {
+				SendFailure(he, inv.Event, inv.Handler.GetName(), T(he, "handler.panic"))
+			}
+			handled = true
+		}()
+		return next(he, inv)
+	}
+}
+
+// LogInvocation logs every handled event with its duration
+func LogInvocation(next HandleFunc) HandleFunc {
+	return func(he HandlerEssentials, inv *Invocation) bool {
+		handled := next(he, inv)
+		if handled {
+			he.Logger.Infow("Handled event", "Handler", inv.Handler.GetName(), "Command", inv.Command, "Sub", inv.Sub,
+				"Sender", inv.Event.Sender, "Room", inv.Event.RoomID, "Duration", time.Since(inv.Start))
+		}
+		return handled
+	}
+}
+
+// Before returns a middleware calling hook before the handler. If hook
+// returns false the handler is skipped and the event counts as handled,
+// e.g. for rate limiting.
+func Before(hook func(he HandlerEssentials, inv *Invocation) bool) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(he HandlerEssentials, inv *Invocation) bool {
+			if !hook(he, inv) {
+				return true
+			}
+			return next(he, inv)
+		}
+	}
+}
+
+// After returns a middleware calling hook with the result and duration of
+// the handler, e.g. for metrics. A panic is passed on after the hook ran
+// with handled set to true.
+func After(hook func(he HandlerEssentials, inv *Invocation, handled bool, d time.Duration)) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(he HandlerEssentials, inv *Invocation) bool {
+			start := time.Now()
+			handled := true
+			defer func() {
+				hook(he, inv, handled, time.Since(start))
+			}()
+			handled = next(he, inv)
+			return handled
+		}
+	}
+}
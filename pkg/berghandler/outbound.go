@@ -0,0 +1,253 @@
+package berghandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const defaultSendRetries = 5
+const defaultSendRetryMin = time.Second
+const defaultSendMaxWait = 5 * time.Minute
+
+// shutdownSendMaxWait caps the retry waits once the bot shuts down, it waits
+// 20 seconds for the handlers and their messages
+const shutdownSendMaxWait = 5 * time.Second
+
+type OutboundConfig struct {
+	MaxRetries      int //Retries of a failed send, default 5
+	RetryMinSeconds int //First backoff for transient errors, doubled on every retry, default 1
+	MaxWaitSeconds  int //Give up if the homeserver wants us to wait longer, default 300
+}
+
+type outboundJob struct {
+	send   func() (*mautrix.RespSendEvent, error)
+	result chan outboundResult
+}
+
+type outboundResult struct {
+	resp *mautrix.RespSendEvent
+	err  error
+}
+
+// OutboundQueue is a MatrixClient that sends the events of a room one after
+// another. It waits as long as the homeserver asks on M_LIMIT_EXCEEDED and
+// retries transient failures, permanent ones are returned to the caller.
+// Every call blocks until its event is sent or given up.
+type OutboundQueue struct {
+	ctx      context.Context //Done once the bot shuts down
+	client   MatrixClient
+	logger   *zap.SugaredLogger
+	retries  int
+	retryMin time.Duration
+	maxWait  time.Duration
+	txnID    int32
+	mu       sync.Mutex
+	queues   map[id.RoomID][]outboundJob //The first job of a room is being sent
+}
+
+func NewOutboundQueue(ctx context.Context, client MatrixClient, c OutboundConfig, logger *zap.SugaredLogger) *OutboundQueue {
+	res := new(OutboundQueue)
+	res.ctx = ctx
+	res.client = client
+	res.logger = logger
+	res.retries = c.MaxRetries
+	if res.retries <= 0 {
+		res.retries = defaultSendRetries
+	}
+	res.retryMin = time.Duration(c.RetryMinSeconds) * time.Second
+	if res.retryMin <= 0 {
+		res.retryMin = defaultSendRetryMin
+	}
+	res.maxWait = time.Duration(c.MaxWaitSeconds) * time.Second
+	if res.maxWait <= 0 {
+		res.maxWait = defaultSendMaxWait
+	}
+	res.queues = make(map[id.RoomID][]outboundJob)
+	return res
+}
+
+// newTxnID makes retries of a send idempotent, the homeserver drops a
+// repeated transaction ID instead of posting the event twice.
+func (q *OutboundQueue) newTxnID() string {
+	n := atomic.AddInt32(&q.txnID, 1)
+	return "bergknecht_" + strconv.FormatInt(time.Now().UnixNano(), 10) + "_" + strconv.Itoa(int(n))
+}
+
+func (q *OutboundQueue) enqueue(roomID id.RoomID, send func() (*mautrix.RespSendEvent, error)) (*mautrix.RespSendEvent, error) {
+	job := outboundJob{send: send, result: make(chan outboundResult, 1)}
+	q.mu.Lock()
+	jobs, running := q.queues[roomID]
+	q.queues[roomID] = append(jobs, job)
+	q.mu.Unlock()
+	if !running {
+		go q.runRoom(roomID)
+	}
+	res := <-job.result
+	return res.resp, res.err
+}
+
+// runRoom sends the queued events of a room, the queue entry is removed once
+// it is empty so the next send starts a new runner.
+func (q *OutboundQueue) runRoom(roomID id.RoomID) {
+	for {
+		q.mu.Lock()
+		jobs := q.queues[roomID]
+		if len(jobs) == 0 {
+			delete(q.queues, roomID)
+			q.mu.Unlock()
+			return
+		}
+		job := jobs[0]
+		q.mu.Unlock()
+
+		resp, err := q.run(roomID, job.send)
+		q.mu.Lock()
+		q.queues[roomID] = q.queues[roomID][1:]
+		q.mu.Unlock()
+		job.result <- outboundResult{resp: resp, err: err}
+	}
+}
+
+// wait sleeps d before a retry. Once the bot shuts down only waits up to
+// shutdownSendMaxWait are kept, wait reports false for longer ones.
+func (q *OutboundQueue) wait(d time.Duration) bool {
+	until := time.Now().Add(d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-q.ctx.Done():
+	}
+	rest := time.Until(until)
+	if rest > shutdownSendMaxWait {
+		return false
+	}
+	time.Sleep(rest)
+	return true
+}
+
+// LogUnsent logs the rooms with events that are not sent yet and returns
+// their number, the bot calls it before it exits
+func (q *OutboundQueue) LogUnsent() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for roomID, jobs := range q.queues {
+		if len(jobs) > 0 {
+			q.logger.Warnw("Messages not sent before exit", "Room", roomID, "Count", len(jobs))
+			n += len(jobs)
+		}
+	}
+	return n
+}
+
+func (q *OutboundQueue) run(roomID id.RoomID, send func() (*mautrix.RespSendEvent, error)) (*mautrix.RespSendEvent, error) {
+	backoff := q.retryMin
+	for attempt := 0; ; attempt++ {
+		resp, err := send()
+		if err == nil {
+			return resp, nil
+		}
+		wait, transient := retryAfter(err, backoff)
+		if !transient {
+			return nil, err
+		}
+		if attempt >= q.retries {
+			return nil, errors.New("Giving up after " + strconv.Itoa(attempt+1) + " attempts: " + err.Error())
+		}
+		if wait > q.maxWait {
+			return nil, errors.New("Homeserver wants us to wait " + wait.String() + ": " + err.Error())
+		}
+		q.logger.Warnw("Sending failed, retrying", "Room", roomID, "Attempt", attempt+1, "Wait", wait, "Error", err)
+		if !q.wait(wait) {
+			return nil, errors.New("Shutting down, not waiting " + wait.String() + " to retry: " + err.Error())
+		}
+		backoff *= 2
+	}
+}
+
+// retryAfter reports whether err is transient and how long to wait before
+// the next attempt, the homeserver's retry_after_ms wins over backoff.
+func retryAfter(err error, backoff time.Duration) (time.Duration, bool) {
+	var httpErr mautrix.HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0, false
+	}
+	if httpErr.RespError != nil && httpErr.RespError.ErrCode == mautrix.MLimitExceeded.ErrCode {
+		if ms, ok := httpErr.RespError.ExtraData["retry_after_ms"].(float64); ok && ms > 0 {
+			return time.Duration(ms) * time.Millisecond, true
+		}
+		return backoff, true
+	}
+	if httpErr.Response == nil {
+		// No response at all means the connection failed
+		return backoff, httpErr.RespError == nil
+	}
+	code := httpErr.Response.StatusCode
+	return backoff, code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+func (q *OutboundQueue) SendText(roomID id.RoomID, text string) (*mautrix.RespSendEvent, error) {
+	return q.SendMessageEvent(roomID, event.EventMessage, &event.MessageEventContent{MsgType: event.MsgText, Body: text})
+}
+
+func (q *OutboundQueue) SendMessageEvent(roomID id.RoomID, eventType event.Type, contentJSON interface{}, extra ...mautrix.ReqSendEvent) (*mautrix.RespSendEvent, error) {
+	var req mautrix.ReqSendEvent
+	if len(extra) > 0 {
+		req = extra[0]
+	}
+	if req.TransactionID == "" {
+		req.TransactionID = q.newTxnID()
+	}
+	return q.enqueue(roomID, func() (*mautrix.RespSendEvent, error) {
+		return q.client.SendMessageEvent(roomID, eventType, contentJSON, req)
+	})
+}
+
+func (q *OutboundQueue) SendStateEvent(roomID id.RoomID, eventType event.Type, stateKey string, contentJSON interface{}) (*mautrix.RespSendEvent, error) {
+	return q.enqueue(roomID, func() (*mautrix.RespSendEvent, error) {
+		return q.client.SendStateEvent(roomID, eventType, stateKey, contentJSON)
+	})
+}
+
+func (q *OutboundQueue) SendReaction(roomID id.RoomID, eventID id.EventID, reaction string) (*mautrix.RespSendEvent, error) {
+	return q.SendMessageEvent(roomID, event.EventReaction, &event.ReactionEventContent{
+		RelatesTo: event.RelatesTo{EventID: eventID, Type: event.RelAnnotation, Key: reaction},
+	})
+}
+
+func (q *OutboundQueue) RedactEvent(roomID id.RoomID, eventID id.EventID, extra ...mautrix.ReqRedact) (*mautrix.RespSendEvent, error) {
+	var req mautrix.ReqRedact
+	if len(extra) > 0 {
+		req = extra[0]
+	}
+	if req.TxnID == "" {
+		req.TxnID = q.newTxnID()
+	}
+	return q.enqueue(roomID, func() (*mautrix.RespSendEvent, error) {
+		return q.client.RedactEvent(roomID, eventID, req)
+	})
+}
+
+func (q *OutboundQueue) StateEvent(roomID id.RoomID, eventType event.Type, stateKey string, outContent interface{}) error {
+	return q.client.StateEvent(roomID, eventType, stateKey, outContent)
+}
+
+func (q *OutboundQueue) JoinRoom(roomIDorAlias, serverName string, content interface{}) (*mautrix.RespJoinRoom, error) {
+	return q.client.JoinRoom(roomIDorAlias, serverName, content)
+}
+
+func (q *OutboundQueue) LeaveRoom(roomID id.RoomID, optionalReq ...*mautrix.ReqLeave) (*mautrix.RespLeaveRoom, error) {
+	return q.client.LeaveRoom(roomID, optionalReq...)
+}
@@ -0,0 +1,90 @@
+package berghandler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// limitedClient answers every send with M_LIMIT_EXCEEDED and retry_after_ms
+// of retryAfter until limited sends were rejected
+type limitedClient struct {
+	MatrixClient
+	retryAfter time.Duration
+	limited    int32
+	sends      int32
+}
+
+func (c *limitedClient) SendMessageEvent(roomID id.RoomID, eventType event.Type, contentJSON interface{}, extra ...mautrix.ReqSendEvent) (*mautrix.RespSendEvent, error) {
+	if atomic.AddInt32(&c.sends, 1) <= c.limited {
+		return nil, mautrix.HTTPError{
+			Request:  &http.Request{Method: http.MethodPut, URL: &url.URL{Path: "/send"}},
+			Response: &http.Response{StatusCode: http.StatusTooManyRequests},
+			RespError: &mautrix.RespError{ErrCode: mautrix.MLimitExceeded.ErrCode,
+				ExtraData: map[string]interface{}{"retry_after_ms": float64(c.retryAfter.Milliseconds())}},
+		}
+	}
+	return &mautrix.RespSendEvent{EventID: "$sent"}, nil
+}
+
+func TestOutboundShutdownStopsLongWaits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &limitedClient{retryAfter: 4 * time.Minute, limited: 100}
+	q := NewOutboundQueue(ctx, c, OutboundConfig{}, zap.NewNop().Sugar())
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := q.SendText("!room:test", "hallo")
+			errs <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for q.LogUnsent() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v messages queued, want 2", q.LogUnsent())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("send succeeded although the homeserver was limiting")
+			}
+		case <-time.After(shutdownSendMaxWait):
+			t.Fatal("send kept waiting for the homeserver after the shutdown")
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("giving up took %v", d)
+	}
+	if n := q.LogUnsent(); n != 0 {
+		t.Errorf("%v messages still queued", n)
+	}
+}
+
+func TestOutboundShutdownKeepsShortWaits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &limitedClient{retryAfter: 10 * time.Millisecond, limited: 1}
+	q := NewOutboundQueue(ctx, c, OutboundConfig{}, zap.NewNop().Sugar())
+	_, err := q.SendText("!room:test", "hallo")
+	if err != nil {
+		t.Errorf("short retry during the shutdown failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&c.sends); n != 2 {
+		t.Errorf("%v sends, want the retry", n)
+	}
+}
@@ -0,0 +1,358 @@
+package berghandler
+
+import (
+	"errors"
+	"html"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+type ParamType int
+
+const (
+	ParamString ParamType = iota
+	ParamInt
+	ParamMoney
+	ParamUser
+	ParamOrder
+	ParamEnum
+	ParamBool
+)
+
+type Param struct {
+	Name     string    //Shown as $Name in the usage and key in Args
+	Type     ParamType //How the argument gets validated and converted
+	Optional bool      //Optional parameters have to follow the required ones
+	Enum     []string  //Allowed values of a ParamEnum
+	RawCase  bool      //Keep the case of a ParamString, otherwise it gets lowercased
+}
+
+// Args holds the parsed arguments of a command by parameter name, optional
+// parameters that were not given are missing.
+type Args map[string]interface{}
+
+type BergEventArgsFunction func(he HandlerEssentials, evt *event.Event, args Args) bool
+
+var orderNameRegex = regexp.MustCompile(`^[\p{Ll} ]+-[\p{Ll} ]+-[\p{Ll} ]+$`)
+var namedArgRegex = regexp.MustCompile(`^(--)?([\p{L}][\p{L}\d-]*)(?:(=)(.*))?$`)
+var moneyRegex = regexp.MustCompile(`^\d+(\.\d+)?$`)
+var matrixToRegex = regexp.MustCompile(`^https://matrix\.to/#/((?:@|%40)[^/?]+)`)
+var pillRegex = regexp.MustCompile(`<a href="https://matrix\.to/#/((?:@|%40)[^"/?]+)[^"]*">([^<]+)</a>`)
+
+func (a Args) Has(name string) bool {
+	_, ex := a[name]
+	return ex
+}
+
+func (a Args) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+func (a Args) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+func (a Args) Money(name string) float64 {
+	v, _ := a[name].(float64)
+	return v
+}
+
+func (a Args) Bool(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+func (a Args) User(name string) id.UserID {
+	v, _ := a[name].(id.UserID)
+	return v
+}
+
+func (p Param) usage() string {
+	if p.Type == ParamBool {
+		return "--" + strings.ToLower(p.Name)
+	}
+	if p.Type == ParamEnum {
+		return "$" + p.Name + "(" + strings.Join(p.Enum, "|") + ")"
+	}
+	return "$" + p.Name
+}
+
+func hasOptional(params []Param) bool {
+	for _, p := range params {
+		if p.Optional {
+			return true
+		}
+	}
+	return false
+}
+
+func generateUsage(params []Param) string {
+	var required, optional []string
+	for _, p := range params {
+		if p.Optional {
+			optional = append(optional, p.usage())
+		} else {
+			required = append(required, p.usage())
+		}
+	}
+	result := strings.Join(required, " ")
+	if len(optional) > 0 {
+		if result != "" {
+			result += " "
+		}
+		result += "[" + strings.Join(optional, " ") + "]"
+	}
+	return result
+}
+
+// parseMoney accepts a positive amount like 12,50€, NaN, infinity,
+// exponents and negative amounts are no money
+func parseMoney(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "€"))
+	value = strings.Replace(value, ",", ".", 1)
+	if !moneyRegex.MatchString(value) {
+		return 0, errors.New("not an amount of money")
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f < 0 {
+		return 0, errors.New("not an amount of money")
+	}
+	return f, nil
+}
+
+// ResolveMentions returns the body of m with the display names of mention
+// pills replaced by the Matrix IDs, clients only put the link of a pill into
+// the formatted body.
+func ResolveMentions(m *event.MessageEventContent) string {
+	body := m.Body
+	if m.Format != event.FormatHTML {
+		return body
+	}
+	pos := 0
+	for _, p := range pillRegex.FindAllStringSubmatch(m.FormattedBody, -1) {
+		user, err := url.PathUnescape(p[1])
+		if err != nil {
+			continue
+		}
+		name := html.UnescapeString(p[2])
+		i := indexWord(body, name, pos)
+		if i < 0 {
+			continue
+		}
+		body = body[:i] + user + body[i+len(name):]
+		pos = i + len(user)
+	}
+	return body
+}
+
+// indexWord returns the first index of name in s from pos on that is not
+// part of a longer word, clients follow a mention with ":" or ","
+func indexWord(s, name string, pos int) int {
+	for pos <= len(s) {
+		i := strings.Index(s[pos:], name)
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		end := i + len(name)
+		before := i == 0 || unicode.IsSpace(rune(s[i-1]))
+		after := end == len(s) || unicode.IsSpace(rune(s[end])) || s[end] == ':' || s[end] == ','
+		if before && after {
+			return i
+		}
+		pos = i + 1
+	}
+	return -1
+}
+
+func parseUser(value string) (id.UserID, error) {
+	m := matrixToRegex.FindStringSubmatch(value)
+	if m != nil {
+		unescaped, err := url.PathUnescape(m[1])
+		if err != nil {
+			return "", errors.New("not a Matrix user")
+		}
+		value = unescaped
+	}
+	user := id.UserID(value)
+	_, _, err := user.Parse()
+	if err != nil || !strings.HasPrefix(value, "@") {
+		return "", errors.New("not a Matrix user")
+	}
+	return user, nil
+}
+
+func (p Param) parse(value string) (interface{}, error) {
+	switch p.Type {
+	case ParamInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, NewMessageError("args.int", p.Name)
+		}
+		return i, nil
+	case ParamMoney:
+		f, err := parseMoney(value)
+		if err != nil {
+			return nil, NewMessageError("args.money", p.Name)
+		}
+		return f, nil
+	case ParamUser:
+		u, err := parseUser(value)
+		if err != nil {
+			return nil, NewMessageError("args.user", p.Name)
+		}
+		return u, nil
+	case ParamOrder:
+		value = strings.ToLower(value)
+		if !orderNameRegex.MatchString(value) {
+			return nil, NewMessageError("args.order", p.Name)
+		}
+		return value, nil
+	case ParamBool:
+		switch strings.ToLower(value) {
+		case "", "1", "ja", "an", "true", "yes", "on":
+			return true, nil
+		case "0", "nein", "aus", "false", "no", "off":
+			return false, nil
+		}
+		return nil, NewMessageError("args.bool", p.Name)
+	case ParamEnum:
+		value = strings.ToLower(value)
+		for _, e := range p.Enum {
+			if strings.ToLower(e) == value {
+				return e, nil
+			}
+		}
+		return nil, NewMessageError("args.enum", p.Name, strings.Join(p.Enum, ", "))
+	}
+	if p.RawCase {
+		return value, nil
+	}
+	return strings.ToLower(value), nil
+}
+
+// SplitWords splits a message at whitespace, double quotes group words and
+// are removed, "" inside quotes is a literal quote.
+func SplitWords(message string) ([]string, error) {
+	var result []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	runes := []rune(message)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' && quoted && i+1 < len(runes) && runes[i+1] == '"':
+			word.WriteRune(r)
+			i++
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case unicode.IsSpace(r) && !quoted:
+			if inWord {
+				result = append(result, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return result, NewMessageError("args.quote")
+	}
+	if inWord {
+		result = append(result, word.String())
+	}
+	return result, nil
+}
+
+func findParam(params []Param, name string) (Param, bool) {
+	for _, p := range params {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// splitNamed separates key=value, --key=value, --key value and --flag words
+// from the positional ones. Words with a key that is no parameter name stay
+// positional, only unknown --options are an error.
+func splitNamed(params []Param, words []string) (map[string]string, []string, error) {
+	named := make(map[string]string)
+	var positional []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		m := namedArgRegex.FindStringSubmatch(w)
+		if m == nil {
+			positional = append(positional, w)
+			continue
+		}
+		dashed, key, hasValue, value := m[1] != "", m[2], m[3] != "", m[4]
+		p, ex := findParam(params, key)
+		if !ex {
+			if dashed {
+				return nil, nil, NewMessageError("args.unknownoption", key)
+			}
+			positional = append(positional, w)
+			continue
+		}
+		if !dashed && !hasValue {
+			positional = append(positional, w)
+			continue
+		}
+		if dashed && !hasValue && p.Type != ParamBool {
+			if i+1 >= len(words) {
+				return nil, nil, NewMessageError("args.missingafter", p.Name, key)
+			}
+			i++
+			value = words[i]
+		}
+		if _, ex := named[p.Name]; ex {
+			return nil, nil, NewMessageError("args.twice", p.Name)
+		}
+		named[p.Name] = value
+	}
+	return named, positional, nil
+}
+
+// ParseArgs maps named words onto their params and the remaining words onto
+// the other params in order, then converts them
+func ParseArgs(params []Param, words []string) (Args, error) {
+	result := make(Args)
+	named, positional, err := splitNamed(params, words)
+	if err != nil {
+		return result, err
+	}
+	for _, p := range params {
+		value, ex := named[p.Name]
+		if !ex && p.Type != ParamBool && len(positional) > 0 {
+			value, positional, ex = positional[0], positional[1:], true
+		}
+		if !ex {
+			if !p.Optional {
+				return result, NewMessageError("args.missing", p.Name)
+			}
+			continue
+		}
+		v, err := p.parse(value)
+		if err != nil {
+			return result, err
+		}
+		result[p.Name] = v
+	}
+	if len(positional) > 0 {
+		return result, NewMessageError("args.toomany")
+	}
+	return result, nil
+}
@@ -0,0 +1,269 @@
+package berghandler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// errKey returns the message key of err, "" for nil
+func errKey(err error) string {
+	if err == nil {
+		return ""
+	}
+	if me, ok := err.(MessageError); ok {
+		return me.Key
+	}
+	return err.Error()
+}
+
+var testParams = []Param{
+	{Name: "Bestellung", Type: ParamOrder},
+	{Name: "Anzahl", Type: ParamInt},
+	{Name: "Gezahlt", Type: ParamMoney, Optional: true},
+	{Name: "Wer", Type: ParamUser, Optional: true},
+}
+
+func TestParseArgsTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []Param
+		words   []string
+		want    Args
+		wantErr string
+	}{
+		{
+			name:  "required only",
+			words: []string{"Drei-Rote-Affen", "2"},
+			want:  Args{"Bestellung": "drei-rote-affen", "Anzahl": 2},
+		},
+		{
+			name:  "all",
+			words: []string{"drei-rote-affen", "2", "12,50€", "https://matrix.to/#/@max:matrix.org"},
+			want:  Args{"Bestellung": "drei-rote-affen", "Anzahl": 2, "Gezahlt": 12.5, "Wer": id.UserID("@max:matrix.org")},
+		},
+		{name: "missing", words: []string{"drei-rote-affen"}, wantErr: "args.missing"},
+		{name: "too many", words: []string{"drei-rote-affen", "2", "3", "@max:matrix.org", "extra"}, wantErr: "args.toomany"},
+		{name: "no int", words: []string{"drei-rote-affen", "zwei"}, wantErr: "args.int"},
+		{name: "no money", words: []string{"drei-rote-affen", "2", "viel"}, wantErr: "args.money"},
+		{name: "money NaN", words: []string{"drei-rote-affen", "2", "NaN"}, wantErr: "args.money"},
+		{name: "money Inf", words: []string{"drei-rote-affen", "2", "Inf"}, wantErr: "args.money"},
+		{name: "money negative", words: []string{"drei-rote-affen", "2", "-5"}, wantErr: "args.money"},
+		{name: "money exponent", words: []string{"drei-rote-affen", "2", "1e300"}, wantErr: "args.money"},
+		{name: "money too big", words: []string{"drei-rote-affen", "2", "1" + strings.Repeat("0", 400)}, wantErr: "args.money"},
+		{
+			name:  "money plain",
+			words: []string{"drei-rote-affen", "2", "42"},
+			want:  Args{"Bestellung": "drei-rote-affen", "Anzahl": 2, "Gezahlt": 42.0},
+		},
+		{name: "no user", words: []string{"drei-rote-affen", "2", "1", "max"}, wantErr: "args.user"},
+		{name: "no order", words: []string{"pizza", "2"}, wantErr: "args.order"},
+		{
+			name:   "string case",
+			params: []Param{{Name: "Artikel", Type: ParamString}, {Name: "Kommentar", Type: ParamString, RawCase: true}},
+			words:  []string{"Margherita", "Ohne Zwiebeln"},
+			want:   Args{"Artikel": "margherita", "Kommentar": "Ohne Zwiebeln"},
+		},
+		{
+			name:   "enum",
+			params: []Param{{Name: "Sprache", Type: ParamEnum, Enum: []string{"de", "en"}}},
+			words:  []string{"EN"},
+			want:   Args{"Sprache": "en"},
+		},
+		{
+			name:    "enum unknown",
+			params:  []Param{{Name: "Sprache", Type: ParamEnum, Enum: []string{"de", "en"}}},
+			words:   []string{"fr"},
+			wantErr: "args.enum",
+		},
+		{name: "no params", params: []Param{}, words: []string{"x"}, wantErr: "args.toomany"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			if params == nil {
+				params = testParams
+			}
+			got, err := ParseArgs(params, tt.words)
+			if errKey(err) != tt.wantErr {
+				t.Fatalf("ParseArgs(%q) error = %v, want %q", tt.words, err, tt.wantErr)
+			}
+			if tt.wantErr == "" && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseArgs(%q) = %v, want %v", tt.words, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+		wantErr string
+	}{
+		{message: "  add  drei-rote-affen margherita ", want: []string{"add", "drei-rote-affen", "margherita"}},
+		{message: `add "drei rote" affen`, want: []string{"add", "drei rote", "affen"}},
+		{message: `kommentar="ohne Zwiebeln"`, want: []string{"kommentar=ohne Zwiebeln"}},
+		{message: `say "er sagte ""hallo"""`, want: []string{"say", `er sagte "hallo"`}},
+		{message: `a "" b`, want: []string{"a", "", "b"}},
+		{message: `add "drei rote`, wantErr: "args.quote"},
+		{message: "", want: nil},
+	}
+	for _, tt := range tests {
+		got, err := SplitWords(tt.message)
+		if errKey(err) != tt.wantErr {
+			t.Errorf("SplitWords(%q) error = %v, want %q", tt.message, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr == "" && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitWords(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+var namedParams = []Param{
+	{Name: "Bestellung", Type: ParamOrder},
+	{Name: "Artikel", Type: ParamString},
+	{Name: "Anzahl", Type: ParamInt, Optional: true},
+	{Name: "Kommentar", Type: ParamString, Optional: true, RawCase: true},
+	{Name: "Bar", Type: ParamBool, Optional: true},
+}
+
+func TestParseArgsNamed(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    Args
+		wantErr string
+	}{
+		{
+			name:    "key=value",
+			message: "drei-rote-affen margherita anzahl=2",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Anzahl": 2},
+		},
+		{
+			name:    "--key value",
+			message: "drei-rote-affen --anzahl 2 margherita",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Anzahl": 2},
+		},
+		{
+			name:    "--key=value",
+			message: "--ANZAHL=2 drei-rote-affen margherita",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Anzahl": 2},
+		},
+		{
+			name:    "named before positional",
+			message: `drei-rote-affen margherita kommentar="Ohne Zwiebeln" 3`,
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Anzahl": 3, "Kommentar": "Ohne Zwiebeln"},
+		},
+		{
+			name:    "unknown key stays positional",
+			message: "drei-rote-affen x=y",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "x=y"},
+		},
+		{
+			name:    "key without value stays positional",
+			message: "drei-rote-affen anzahl",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "anzahl"},
+		},
+		{name: "unknown option", message: "drei-rote-affen margherita --foo", wantErr: "args.unknownoption"},
+		{name: "missing value", message: "drei-rote-affen margherita --anzahl", wantErr: "args.missingafter"},
+		{name: "twice", message: "drei-rote-affen margherita anzahl=2 --anzahl 3", wantErr: "args.twice"},
+		{name: "too many", message: "drei-rote-affen margherita 2 scharf extra", wantErr: "args.toomany"},
+		{
+			name:    "bool flag",
+			message: "drei-rote-affen margherita --bar",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Bar": true},
+		},
+		{
+			name:    "bool value",
+			message: "drei-rote-affen margherita --bar=nein",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Bar": false},
+		},
+		{
+			name:    "bool english",
+			message: "drei-rote-affen margherita bar=yes",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Bar": true},
+		},
+		{
+			name:    "bool off",
+			message: "drei-rote-affen margherita bar=off",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Bar": false},
+		},
+		{name: "bool invalid", message: "drei-rote-affen margherita --bar=vielleicht", wantErr: "args.bool"},
+		{
+			name:    "bool is never positional",
+			message: "drei-rote-affen margherita 2 ja",
+			want:    Args{"Bestellung": "drei-rote-affen", "Artikel": "margherita", "Anzahl": 2, "Kommentar": "ja"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := SplitWords(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseArgs(namedParams, words)
+			if errKey(err) != tt.wantErr {
+				t.Fatalf("ParseArgs(%q) error = %v, want %q", tt.message, err, tt.wantErr)
+			}
+			if tt.wantErr == "" && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseArgs(%q) = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgsMentionPill(t *testing.T) {
+	params := []Param{{Name: "Bestellung", Type: ParamOrder}, {Name: "Wer", Type: ParamUser}}
+	tests := []struct {
+		name string
+		body string
+		html string
+		want id.UserID
+	}{
+		{
+			name: "pill",
+			body: "drei-rote-affen Alice Liddell",
+			html: `drei-rote-affen <a href="https://matrix.to/#/@alice:example.org">Alice Liddell</a>`,
+			want: "@alice:example.org",
+		},
+		{
+			name: "escaped pill",
+			body: "drei-rote-affen Kim & Co",
+			html: `drei-rote-affen <a href="https://matrix.to/#/%40kim%3Aexample.org?via=example.org">Kim &amp; Co</a>`,
+			want: "@kim:example.org",
+		},
+		{
+			name: "name also in the text",
+			body: "drei-rote-affen drei",
+			html: `drei-rote-affen <a href="https://matrix.to/#/@drei:example.org">drei</a>`,
+			want: "@drei:example.org",
+		},
+		{name: "plain text", body: "drei-rote-affen @max:matrix.org", want: "@max:matrix.org"},
+		{name: "link in body", body: "drei-rote-affen https://matrix.to/#/%40max%3Amatrix.org", want: "@max:matrix.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &event.MessageEventContent{MsgType: event.MsgText, Body: tt.body}
+			if tt.html != "" {
+				m.Format = event.FormatHTML
+				m.FormattedBody = tt.html
+			}
+			words, err := SplitWords(ResolveMentions(m))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseArgs(params, words)
+			if err != nil {
+				t.Fatalf("ParseArgs(%q) error = %v", words, err)
+			}
+			if got.User("Wer") != tt.want || got.String("Bestellung") != "drei-rote-affen" {
+				t.Errorf("ParseArgs(%q) = %v, want %v", words, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package berghandler
+
+import (
+	"errors"
+	"strings"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const defaultAdminPowerLevel = 100
+
+// Role of a user in a room, higher roles include the lower ones
+type Role int
+
+const (
+	RoleGuest Role = iota
+	RoleMember
+	RoleAdmin
+)
+
+type PermissionConfig struct {
+	Admins           []string //Users that are admin in every room
+	Members          []string //Users that are at least member in every room
+	Guests           []string //Users that are only guest, overrides power levels
+	PowerLevels      bool     //Derive the role from the room power levels
+	AdminPowerLevel  int      //Power level needed for admin, default 100
+	MemberPowerLevel int      //Power level needed for member
+	DefaultRole      string   //Role of everyone else: admin, member or guest, default member
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleMember:
+		return "member"
+	}
+	return "guest"
+}
+
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(s) {
+	case "admin":
+		return RoleAdmin, nil
+	case "member", "":
+		return RoleMember, nil
+	case "guest":
+		return RoleGuest, nil
+	}
+	return RoleGuest, errors.New("Unknown role: " + s)
+}
+
+func containsUser(list []string, userID id.UserID) bool {
+	for _, u := range list {
+		if u == userID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleOf returns the role of userID in roomID. Configured users come first,
+// then the power levels if enabled, then the default role.
+func RoleOf(he HandlerEssentials, roomID id.RoomID, userID id.UserID) Role {
+	pc := he.Permissions
+	if containsUser(pc.Admins, userID) {
+		return RoleAdmin
+	}
+	if containsUser(pc.Guests, userID) {
+		return RoleGuest
+	}
+	if containsUser(pc.Members, userID) {
+		return RoleMember
+	}
+	if pc.PowerLevels {
+		var pl event.PowerLevelsEventContent
+		err := he.Client.StateEvent(roomID, event.StatePowerLevels, "", &pl)
+		if err == nil {
+			adminLevel := pc.AdminPowerLevel
+			if adminLevel == 0 {
+				adminLevel = defaultAdminPowerLevel
+			}
+			level := pl.GetUserLevel(userID)
+			if level >= adminLevel {
+				return RoleAdmin
+			}
+			if level >= pc.MemberPowerLevel {
+				return RoleMember
+			}
+			return RoleGuest
+		}
+		he.Logger.Warnw("Error getting power levels", "Room", roomID, "Error", err)
+	}
+	r, err := ParseRole(pc.DefaultRole)
+	if err != nil {
+		he.Logger.Warnw("Invalid default role", "Role", pc.DefaultRole)
+	}
+	return r
+}
+
+// HasRole reports whether the sender of evt has at least role in its room
+func HasRole(he HandlerEssentials, evt *event.Event, role Role) bool {
+	if role == RoleGuest {
+		return true
+	}
+	return RoleOf(he, evt.RoomID, evt.Sender) >= role
+}
@@ -0,0 +1,177 @@
+package berghandler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"maunium.net/go/mautrix/id"
+)
+
+const rateLimitPruneInterval = 10 * time.Minute
+
+// unknownSub is the sub-command all unknown ones share their buckets under,
+// otherwise every made up sub-command would start with a full bucket
+const unknownSub = "?"
+
+// BergSubCommander is implemented by handlers with sub-commands
+type BergSubCommander interface {
+	HasSubCommand(sub string) bool
+}
+
+// limitedSub returns sub if it is a sub-command of h, unknownSub otherwise
+func limitedSub(h BergEventHandler, sub string) string {
+	if sc, ok := h.(BergSubCommander); ok && sc.HasSubCommand(sub) {
+		return sub
+	}
+	return unknownSub
+}
+
+// RateLimit is a token bucket, Burst commands at once that refill with
+// PerMinute. A Burst of 0 means unlimited.
+type RateLimit struct {
+	Burst     int
+	PerMinute int //Default Burst
+}
+
+type CommandRateLimit struct {
+	User RateLimit //Per sender
+	Room RateLimit //Per room
+}
+
+type RateLimitConfig struct {
+	Enabled  bool
+	Default  CommandRateLimit                       //For every sub-command not listed below
+	Commands map[string]map[string]CommandRateLimit //Per command and sub-command
+}
+
+func (c RateLimitConfig) limitFor(cmd, sub string) CommandRateLimit {
+	if l, ex := c.Commands[cmd][sub]; ex {
+		return l
+	}
+	return c.Default
+}
+
+func (rl RateLimit) perSecond() float64 {
+	if rl.PerMinute <= 0 {
+		return float64(rl.Burst) / 60
+	}
+	return float64(rl.PerMinute) / 60
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	limit    RateLimit
+	notified bool //The sender got the throttled notice since the last command
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.perSecond())
+	b.last = now
+}
+
+func (b *bucket) wait() time.Duration {
+	return time.Duration((1 - b.tokens) / b.limit.perSecond() * float64(time.Second))
+}
+
+// RateLimiter limits the commands per sender and per room with token buckets,
+// one per sub-command.
+type RateLimiter struct {
+	mu        sync.Mutex
+	conf      RateLimitConfig
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter(c RateLimitConfig) *RateLimiter {
+	res := new(RateLimiter)
+	res.conf = c
+	res.buckets = make(map[string]*bucket)
+	res.now = time.Now
+	res.lastPrune = res.now()
+	return res
+}
+
+func (r *RateLimiter) getBucket(key string, limit RateLimit, now time.Time) *bucket {
+	b, ex := r.buckets[key]
+	if !ex || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		r.buckets[key] = b
+	}
+	b.refill(now)
+	return b
+}
+
+// prune drops the full buckets, they behave like new ones
+func (r *RateLimiter) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	for k, b := range r.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(r.buckets, k)
+		}
+	}
+	r.lastPrune = now
+}
+
+// Allow takes a token for the sub-command from the buckets of sender and
+// room, sub has to be a known sub-command or unknownSub. If one is empty
+// nothing is taken and Allow returns the time until the next command is
+// possible and whether the sender was already told.
+func (r *RateLimiter) Allow(roomID id.RoomID, sender id.UserID, cmd, sub string) (bool, time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.prune(now)
+	limit := r.conf.limitFor(cmd, sub)
+	var buckets []*bucket
+	if limit.User.Burst > 0 {
+		buckets = append(buckets, r.getBucket("user|"+sender.String()+"|"+cmd+"|"+sub, limit.User, now))
+	}
+	if limit.Room.Burst > 0 {
+		buckets = append(buckets, r.getBucket("room|"+roomID.String()+"|"+cmd+"|"+sub, limit.Room, now))
+	}
+	var wait time.Duration
+	notified := false
+	for _, b := range buckets {
+		if b.tokens < 1 {
+			if w := b.wait(); w > wait {
+				wait = w
+			}
+			notified = notified || b.notified
+			b.notified = true
+		}
+	}
+	if wait > 0 {
+		return false, wait, notified
+	}
+	for _, b := range buckets {
+		b.tokens--
+		b.notified = false
+	}
+	return true, 0, false
+}
+
+// Middleware throttles the commands of all handlers, a throttled sender gets
+// one notice until the limit is over.
+func (r *RateLimiter) Middleware() Middleware {
+	return Before(func(he HandlerEssentials, inv *Invocation) bool {
+		if !inv.IsCommand() {
+			return true
+		}
+		ok, wait, notified := r.Allow(inv.Event.RoomID, inv.Event.Sender, inv.Command, limitedSub(inv.Handler, inv.Sub))
+		if ok {
+			return true
+		}
+		he.Logger.Infow("Command throttled", "Handler", inv.Handler.GetName(), "Command", inv.Command, "Sub", inv.Sub,
+			"Sender", inv.Event.Sender, "Room", inv.Event.RoomID, "Wait", wait)
+		if !notified {
+			SendFailure(he, inv.Event, inv.Handler.GetName(), Tf(he, "ratelimit.throttled", int(math.Ceil(wait.Seconds()))))
+		}
+		return false
+	})
+}
@@ -0,0 +1,60 @@
+package berghandler
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+)
+
+// subHandler is a handler with the sub-commands of its set
+type subHandler struct {
+	set SubHandlers
+}
+
+func (h subHandler) Handle(he HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
+	return false
+}
+func (h subHandler) GetName() string                  { return "TestHandler" }
+func (h subHandler) GetCommand() string               { return "test" }
+func (h subHandler) Prime(he HandlerEssentials) error { return nil }
+func (h subHandler) HasSubCommand(sub string) bool    { return h.set.Has(sub) }
+
+func TestRateLimitUnknownSubs(t *testing.T) {
+	r := NewRateLimiter(RateLimitConfig{Enabled: true, Default: CommandRateLimit{User: RateLimit{Burst: 3, PerMinute: 1}}})
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	h := subHandler{set: SubHandlers{"show": SubHandlerSet{}}}
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		ok, _, _ := r.Allow("!room:test", "@spam:test", "test", limitedSub(h, "erfunden"+strconv.Itoa(i)))
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("%v of 10 different unknown sub-commands allowed, want the burst of 3", allowed)
+	}
+
+	// Known sub-commands keep their own bucket
+	for _, sub := range []string{"show", "help"} {
+		if ok, _, _ := r.Allow("!room:test", "@spam:test", "test", limitedSub(h, sub)); !ok {
+			t.Errorf("%v was throttled by the unknown sub-commands", sub)
+		}
+	}
+
+	// Handlers without sub-commands share one bucket for all words
+	allowed = 0
+	for i := 0; i < 10; i++ {
+		ok, _, _ := r.Allow("!room:test", "@spam:test", "echo", limitedSub(struct{ BergEventHandler }{}, "wort"+strconv.Itoa(i)))
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("%v of 10 echo commands allowed, want the burst of 3", allowed)
+	}
+}
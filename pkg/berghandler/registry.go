@@ -0,0 +1,31 @@
+package berghandler
+
+import (
+	"sort"
+)
+
+var registry = make(map[string]BergEventHandler)
+
+// RegisterHandler makes a handler available under its GetName(). It is meant
+// to be called from the init function of the handler package.
+func RegisterHandler(h BergEventHandler) {
+	name := h.GetName()
+	if _, ex := registry[name]; ex {
+		panic("berghandler: Handler " + name + " registered twice")
+	}
+	registry[name] = h
+}
+
+func GetHandler(name string) (BergEventHandler, bool) {
+	h, ex := registry[name]
+	return h, ex
+}
+
+func RegisteredHandlers() []string {
+	var result []string
+	for k := range registry {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,90 @@
+package berghandler
+
+import (
+	"errors"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	ReactionSuccess = "✅"
+	ReactionFailure = "❌"
+)
+
+// ResponseConfig chooses how a handler answers the event that triggered it
+type ResponseConfig struct {
+	Reply     bool //Answer as reply to the command
+	Thread    bool //Answer inside the thread if the command came from one
+	Reactions bool //Acknowledge with a reaction instead of a text message
+}
+
+// responseRelation returns the relation an answer to evt gets, nil for a
+// new top-level message.
+func responseRelation(he HandlerEssentials, evt *event.Event) *event.RelatesTo {
+	if evt == nil || evt.ID == "" {
+		return nil
+	}
+	var rel *event.RelatesTo
+	if he.Response.Thread {
+		parent := evt.Content.AsMessage().RelatesTo.GetThreadParent()
+		if parent != "" {
+			rel = (&event.RelatesTo{}).SetThread(parent, evt.ID)
+		}
+	}
+	if he.Response.Reply {
+		if rel == nil {
+			rel = &event.RelatesTo{}
+		}
+		rel.SetReplyTo(evt.ID)
+	}
+	return rel
+}
+
+// SendReaction reacts to evt with key, encrypted if the room is
+func SendReaction(he HandlerEssentials, evt *event.Event, handlerName, key string) bool {
+	content := &event.ReactionEventContent{
+		RelatesTo: *(&event.RelatesTo{}).SetAnnotation(evt.ID, key),
+	}
+	_, err := sendEvent(he, evt.RoomID, event.EventReaction, content)
+	if err != nil {
+		he.Logger.Errorw("Error sending Reaction", "Handler", handlerName, "Error", err)
+		return false
+	}
+	return true
+}
+
+// SendSuccess acknowledges evt, with a reaction if the handler is configured
+// for it, otherwise with msg.
+func SendSuccess(he HandlerEssentials, evt *event.Event, handlerName, msg string) bool {
+	if he.Response.Reactions {
+		return SendReaction(he, evt, handlerName, ReactionSuccess)
+	}
+	return SendMessage(he, evt, handlerName, msg)
+}
+
+// SendFailure answers evt with msg and, if the handler is configured for
+// reactions, marks it as failed. The text stays as it says what went wrong.
+func SendFailure(he HandlerEssentials, evt *event.Event, handlerName, msg string) bool {
+	if he.Response.Reactions {
+		SendReaction(he, evt, handlerName, ReactionFailure)
+	}
+	return SendMessage(he, evt, handlerName, msg)
+}
+
+// sendEvent sends content to roomID unless the event being handled timed out
+func sendEvent(he HandlerEssentials, roomID id.RoomID, evtType event.Type, content interface{}) (*mautrix.RespSendEvent, error) {
+	err := he.Context().Err()
+	if err != nil {
+		return nil, errors.New("Not sending, handler gave up: " + err.Error())
+	}
+	if he.Crypto != nil && he.Crypto.IsEncrypted(roomID) {
+		enc, err := he.Crypto.Encrypt(roomID, evtType, content)
+		if err != nil {
+			return nil, errors.New("Error encrypting message: " + err.Error())
+		}
+		return he.Client.SendMessageEvent(roomID, event.EventEncrypted, enc)
+	}
+	return he.Client.SendMessageEvent(roomID, evtType, content)
+}
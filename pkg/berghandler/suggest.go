@@ -0,0 +1,87 @@
+package berghandler
+
+import (
+	"sort"
+	"strings"
+)
+
+const maxSuggestions = 3
+
+// Levenshtein returns the edit distance of a and b ignoring case, swapping
+// two neighbouring characters counts as one edit like in typos
+func Levenshtein(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+// Suggest returns up to three candidates close to input, the closest first.
+// Up to a third of the input length may be wrong, at least one character.
+func Suggest(input string, candidates []string) []string {
+	maxDistance := len([]rune(input)) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	type match struct {
+		candidate string
+		distance  int
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		d := Levenshtein(input, c)
+		if d <= maxDistance {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	var result []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].candidate)
+	}
+	return result
+}
+
+// DidYouMean returns a sentence with the suggestions for input to append to
+// an error message, empty if nothing is close enough
+func DidYouMean(he HandlerEssentials, input string, candidates []string) string {
+	s := Suggest(input, candidates)
+	if len(s) == 0 {
+		return ""
+	}
+	return " " + Tf(he, "suggest.didyoumean", strings.Join(s, ", "))
+}
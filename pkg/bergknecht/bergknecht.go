@@ -1,35 +1,65 @@
 package bergknecht
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/Nerdbergev/Bergknecht/pkg/bergcrypto"
 	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
 	"github.com/Nerdbergev/Bergknecht/pkg/config"
-	"github.com/Nerdbergev/Bergknecht/pkg/handlers/bestellungHandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/metrics"
 	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"github.com/Nerdbergev/Bergknecht/pkg/webhook"
 	"go.uber.org/zap"
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
 )
 
-var handlers []berghandler.BergEventHandler
-var startup time.Time
-
-func init() {
-	h := bestellungHandler.BestellungHandler{}
-	handlers = append(handlers, &h)
-	startup = time.Now()
-}
-
-func doLogin(conf config.Config) (*mautrix.Client, error) {
+func doLogin(conf config.Config, sm *storage.Manager, sugar *zap.SugaredLogger) (*mautrix.Client, error) {
 	client, err := mautrix.NewClient(conf.Serversettings.Homserver, "", "")
 	if err != nil {
 		return nil, errors.New("Error creating Client: " + err.Error())
 	}
 
+	if conf.Serversettings.AccessToken != "" {
+		sugar.Infow("Using configured access token")
+		err = useAccessToken(client, conf.Serversettings.AccessToken, conf.Serversettings.DeviceID)
+		if err != nil {
+			return nil, errors.New("Error using access token: " + err.Error())
+		}
+		return client, nil
+	}
+
+	if conf.Serversettings.PersistSession {
+		restored, err := restoreSession(client, sm)
+		if err != nil {
+			sugar.Warnw("Unable to restore session, falling back to password login", "error", err)
+		} else if restored {
+			sugar.Infow("Restored session", "device", client.DeviceID)
+			return client, nil
+		}
+	}
+
+	err = passwordLogin(client, conf, conf.Serversettings.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Serversettings.PersistSession {
+		err = saveSession(client, sm)
+		if err != nil {
+			sugar.Errorw("Unable to save session", "error", err)
+		}
+	}
+
+	return client, nil
+}
+
+func passwordLogin(client *mautrix.Client, conf config.Config, deviceID string) error {
 	var ident mautrix.UserIdentifier
 	ident.User = conf.Serversettings.Username
 	ident.Type = "m.id.user"
@@ -38,23 +68,14 @@ func doLogin(conf config.Config) (*mautrix.Client, error) {
 	reqLog.Identifier = ident
 	reqLog.Password = conf.Serversettings.Password
 	reqLog.Type = "m.login.password"
+	reqLog.DeviceID = id.DeviceID(deviceID)
+	reqLog.InitialDeviceDisplayName = "Bergknecht"
 	reqLog.StoreCredentials = true
 	reqLog.StoreHomeserverURL = true
 
-	_, err = client.Login(&reqLog)
+	_, err := client.Login(&reqLog)
 	if err != nil {
-		return nil, errors.New("Error logging in: " + err.Error())
-	}
-
-	return client, nil
-}
-
-func joinRooms(client *mautrix.Client, conf config.Config) error {
-	for _, r := range conf.Serversettings.Rooms {
-		_, err := client.JoinRoom(r, conf.Serversettings.Homserver, nil)
-		if err != nil {
-			return errors.New("Error joing Room " + r + ": " + err.Error())
-		}
+		return errors.New("Error logging in: " + err.Error())
 	}
 	return nil
 }
@@ -68,56 +89,172 @@ func isinRoomList(roomID string, roomList []string) bool {
 	return false
 }
 
-func RunBot(conf config.Config) error {
+// eventCutoff returns the oldest timestamp in ms of events that still get
+// processed, older commands are dropped instead of being answered late.
+func eventCutoff(conf config.Config, startup time.Time) int64 {
+	if conf.SyncSettings.CatchUpMinutes < 0 {
+		return 0
+	}
+	return startup.Add(-time.Duration(conf.SyncSettings.CatchUpMinutes) * time.Minute).UnixMilli()
+}
+
+const shutdownTimeout = 20 * time.Second
+
+func shutdownHandlers(he berghandler.HandlerEssentials, handlers []activeHandler) {
+	for _, ah := range handlers {
+		sh, ok := ah.handler.(berghandler.BergShutdownHandler)
+		if !ok {
+			continue
+		}
+		err := sh.Shutdown(he)
+		if err != nil {
+			he.Logger.Errorw("Handler unable to shut down", "handlername", ah.handler.GetName(), "error", err)
+		}
+	}
+}
+
+// RunBot syncs until ctx is cancelled or the sync fails, afterwards it waits
+// for running handlers and lets them shut down.
+func RunBot(ctx context.Context, conf config.Config) error {
 	logger := zap.Must(conf.LoggerSettings.Build())
 	defer logger.Sync() // flushes buffer, if any
 	sugar := logger.Sugar()
 
-	sugar.Infow("Logging in")
-	client, err := doLogin(conf)
+	handlers, err := loadHandlers(conf)
 	if err != nil {
-		return errors.New("Error logging in: " + err.Error())
+		return errors.New("Error loading Handlers: " + err.Error())
 	}
-	sugar.Infow("Joining Rooms")
-	err = joinRooms(client, conf)
+	err = checkLanguages(conf)
 	if err != nil {
-		return errors.New("Error joining in: " + err.Error())
+		return errors.New("Error in RoomSettings: " + err.Error())
 	}
 
+	go func() {
+		err := metrics.Serve(ctx, conf.MetricsSettings, sugar)
+		if err != nil {
+			sugar.Errorw("Metrics listener stopped", "error", err)
+		}
+	}()
+
 	sugar.Infow("Setting up Storage")
 	sm := storage.CreateStorageManager(conf.StorageSettings)
 	defer sm.DeleteCache()
 
+	sugar.Infow("Logging in")
+	client, err := doLogin(conf, sm, sugar)
+	if err != nil {
+		return errors.New("Error logging in: " + err.Error())
+	}
+	sugar.Infow("Joining Rooms")
+	rooms := newRoomList(client, sm, sugar)
+	err = rooms.joinAll(conf.Serversettings.Rooms)
+	if err != nil {
+		return errors.New("Error joining in: " + err.Error())
+	}
+
 	rand.Seed(time.Now().UnixNano())
 
-	he := berghandler.HandlerEssentials{Client: client, Logger: sugar, Storage: sm}
+	perms := conf.Permissions
+	perms.Admins = append(perms.Admins, conf.Serversettings.Admins...)
+	langs := newUserLanguages(sm, sugar)
+	// 429s are left to the outbound queue, it honours retry_after_ms per room
+	client.IgnoreRateLimit = true
+	// Cancelled once the sync stopped, retries must not outlast the shutdown
+	sendCtx, stopSending := context.WithCancel(context.Background())
+	defer stopSending()
+	outbound := berghandler.NewOutboundQueue(sendCtx, metrics.Client{MatrixClient: client}, conf.OutboundSettings, sugar)
+	he := berghandler.HandlerEssentials{Client: outbound, Logger: sugar, Storage: sm, Rooms: rooms, Permissions: perms, Languages: langs}
+
+	client.Store = newSyncStore(sm, sugar)
+	cutoff := eventCutoff(conf, time.Now())
+
+	syncer := client.Syncer.(*mautrix.DefaultSyncer)
+	syncer.OnEventType(event.StateMember, rooms.handleInvite(conf.Serversettings.InviteAllowlist))
+	var ch *bergcrypto.Helper
+	if conf.CryptoSettings.Enabled {
+		sugar.Infow("Setting up Encryption")
+		ch, err = bergcrypto.NewHelper(conf.CryptoSettings, client, sm, sugar)
+		if err != nil {
+			return errors.New("Error setting up encryption: " + err.Error())
+		}
+		defer ch.Close()
+		ch.Register(syncer)
+		he.Crypto = ch
+	}
 
 	sugar.Infow("Loading Handler Data")
-	for _, h := range handlers {
-		err := h.Prime(he)
+	for _, ah := range handlers {
+		err := ah.handler.Prime(he)
 		if err != nil {
-			sugar.Errorw("Hanlder unable to load data", "handlername", h.GetName(), "error", err)
+			sugar.Errorw("Hanlder unable to load data", "handlername", ah.handler.GetName(), "error", err)
 		}
 	}
 
+	webhooks, err := webhook.NewServer(conf.WebhookSettings, he)
+	if err != nil {
+		return errors.New("Error setting up webhooks: " + err.Error())
+	}
+	go func() {
+		err := webhooks.Serve(ctx)
+		if err != nil {
+			sugar.Errorw("Webhook listener stopped", "error", err)
+		}
+	}()
+
 	sugar.Infow("Starting Syncer")
-	syncer := client.Syncer.(*mautrix.DefaultSyncer)
+	dispatcher := berghandler.NewDispatcher(conf.DispatchSettings, sugar)
+	chain := append(berghandler.DefaultChain(), metrics.Middleware())
+	if conf.RateLimits.Enabled {
+		chain = append(chain, berghandler.NewRateLimiter(conf.RateLimits).Middleware())
+	}
 	syncer.OnEvent(func(source mautrix.EventSource, evt *event.Event) {
-		if evt.Timestamp >= startup.UnixMilli() {
-			if (evt.Sender != client.UserID) && (isinRoomList(evt.RoomID.String(), conf.Serversettings.Rooms)) {
-				for _, h := range handlers {
-					handled := h.Handle(he, source, evt)
-					if handled {
-						break
+		metrics.EventReceived(evt)
+		if evt.Type == event.EventEncrypted {
+			if ch == nil {
+				return
+			}
+			decrypted, err := ch.Decrypt(evt)
+			if err != nil {
+				sugar.Warnw("Unable to decrypt event", "room", evt.RoomID, "event", evt.ID, "error", err)
+				return
+			}
+			evt = decrypted
+		}
+		if evt.Timestamp >= cutoff {
+			if (evt.Sender != client.UserID) && (rooms.contains(evt.RoomID)) {
+				rhe := he
+				rhe.Room = roomSettings(conf, evt.RoomID.String())
+				rhe.Active = activeIn(handlers, evt.RoomID.String(), rhe.Room)
+				rhe.UserLanguage = langs.UserLanguage(evt.Sender)
+				dispatcher.Dispatch(evt.RoomID, func(ctx context.Context) {
+					for _, h := range rhe.Active {
+						hhe := rhe
+						hhe.Ctx = ctx
+						hhe.Response = conf.Handlers.Responses[h.GetName()]
+						handled := chain.Handle(hhe, h, source, evt)
+						if handled {
+							break
+						}
 					}
-				}
+				})
 			}
 		}
 	})
-	err = client.Sync()
-	if err != nil {
-		return errors.New("Error syncing: " + err.Error())
+	syncErr := newSupervisor(client, conf, sm, sugar).run(ctx)
+
+	sugar.Infow("Shutting down")
+	metrics.Stopping()
+	stopSending()
+	if !dispatcher.Wait(shutdownTimeout) {
+		sugar.Warnw("Handlers still running after timeout", "timeout", shutdownTimeout)
+	}
+	shutdownHandlers(he, handlers)
+	if n := outbound.LogUnsent(); n > 0 {
+		sugar.Warnw("Exiting with unsent messages", "count", n)
 	}
 
+	if syncErr != nil && !errors.Is(syncErr, context.Canceled) {
+		return errors.New("Error syncing: " + syncErr.Error())
+	}
 	return nil
 }
@@ -0,0 +1,79 @@
+package bergknecht
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/config"
+
+	// Built-in handlers, they register themselves in the berghandler registry
+	_ "github.com/Nerdbergev/Bergknecht/pkg/handlers/bestellungHandler"
+	_ "github.com/Nerdbergev/Bergknecht/pkg/handlers/echoHandler"
+	_ "github.com/Nerdbergev/Bergknecht/pkg/handlers/helpHandler"
+	_ "github.com/Nerdbergev/Bergknecht/pkg/handlers/raumHandler"
+	_ "github.com/Nerdbergev/Bergknecht/pkg/handlers/spracheHandler"
+)
+
+type activeHandler struct {
+	handler berghandler.BergEventHandler
+	rooms   []string
+}
+
+func (ah activeHandler) isActiveIn(roomID string, rs berghandler.RoomSettings) bool {
+	if !rs.IsHandlerEnabled(ah.handler.GetName()) {
+		return false
+	}
+	return len(ah.rooms) == 0 || isinRoomList(roomID, ah.rooms)
+}
+
+// activeIn returns the handlers active in the room in their configured order
+func activeIn(handlers []activeHandler, roomID string, rs berghandler.RoomSettings) []berghandler.BergEventHandler {
+	var result []berghandler.BergEventHandler
+	for _, ah := range handlers {
+		if ah.isActiveIn(roomID, rs) {
+			result = append(result, ah.handler)
+		}
+	}
+	return result
+}
+
+func roomSettings(conf config.Config, roomID string) berghandler.RoomSettings {
+	rs := berghandler.RoomSettings{Prefix: berghandler.DefaultCommandPrefix}
+	rs = rs.Merge(conf.RoomDefaults)
+	return rs.Merge(conf.RoomSettings[roomID])
+}
+
+// checkLanguages errors on room languages without a message catalog
+func checkLanguages(conf config.Config) error {
+	rooms := map[string]berghandler.RoomSettings{"RoomDefaults": conf.RoomDefaults}
+	for r, rs := range conf.RoomSettings {
+		rooms[r] = rs
+	}
+	for r, rs := range rooms {
+		if rs.Language != "" && !berghandler.HasLanguage(rs.Language) {
+			return errors.New("Unknown language " + rs.Language + " for " + r + ", available are: " + strings.Join(berghandler.Languages(), ", "))
+		}
+	}
+	return nil
+}
+
+func loadHandlers(conf config.Config) ([]activeHandler, error) {
+	var result []activeHandler
+	for _, name := range conf.Handlers.Enabled {
+		h, ex := berghandler.GetHandler(name)
+		if !ex {
+			return nil, errors.New("Unknown Handler " + name + ", available are: " + strings.Join(berghandler.RegisteredHandlers(), ", "))
+		}
+		for _, ah := range result {
+			if ah.handler.GetName() == name {
+				return nil, errors.New("Handler " + name + " enabled twice")
+			}
+		}
+		result = append(result, activeHandler{handler: h, rooms: conf.Handlers.Rooms[name]})
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no Handlers enabled, available are: " + strings.Join(berghandler.RegisteredHandlers(), ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package bergknecht
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix/id"
+)
+
+const languagesFile = "languages.toml"
+
+type storedLanguages struct {
+	Users map[string]string
+}
+
+// userLanguages holds the languages users chose with the SpracheHandler,
+// they override the language of the room and are persisted.
+type userLanguages struct {
+	mu     sync.RWMutex
+	users  map[string]string
+	sm     *storage.Manager
+	logger *zap.SugaredLogger
+}
+
+func newUserLanguages(sm *storage.Manager, logger *zap.SugaredLogger) *userLanguages {
+	res := new(userLanguages)
+	res.users = make(map[string]string)
+	res.sm = sm
+	res.logger = logger
+	if !sm.DoesFileExist(storageName, languagesFile, true) {
+		return res
+	}
+	var sl storedLanguages
+	err := sm.DecodeFile(storageName, languagesFile, storage.TOML, true, &sl)
+	if err != nil {
+		logger.Warnw("Unable to load user languages", "error", err)
+		return res
+	}
+	for k, v := range sl.Users {
+		res.users[k] = v
+	}
+	return res
+}
+
+func (ul *userLanguages) UserLanguage(userID id.UserID) string {
+	ul.mu.RLock()
+	defer ul.mu.RUnlock()
+	return ul.users[userID.String()]
+}
+
+// SetUserLanguage stores lang for userID, an empty lang removes it
+func (ul *userLanguages) SetUserLanguage(userID id.UserID, lang string) error {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	if lang == "" {
+		delete(ul.users, userID.String())
+	} else {
+		ul.users[userID.String()] = lang
+	}
+	err := ul.sm.EncodeFile(storageName, languagesFile, storage.TOML, true, storedLanguages{Users: ul.users})
+	if err != nil {
+		return errors.New("Error saving languages: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package bergknecht
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const roomsFile = "rooms.toml"
+
+type joinedRooms struct {
+	Rooms []string
+}
+
+// roomList holds the rooms the bot answers in. Rooms joined or left at
+// runtime are persisted, configured rooms are joined on every start and not
+// stored, so removing them from the config takes effect.
+type roomList struct {
+	mu     sync.RWMutex
+	rooms  map[id.RoomID]bool
+	joined map[id.RoomID]bool //Joined at runtime, by invite or raum join
+	client *mautrix.Client
+	sm     *storage.Manager
+	logger *zap.SugaredLogger
+}
+
+func newRoomList(client *mautrix.Client, sm *storage.Manager, logger *zap.SugaredLogger) *roomList {
+	res := new(roomList)
+	res.rooms = make(map[id.RoomID]bool)
+	res.joined = make(map[id.RoomID]bool)
+	res.client = client
+	res.sm = sm
+	res.logger = logger
+	return res
+}
+
+func (rl *roomList) load() ([]string, error) {
+	var jr joinedRooms
+	if !rl.sm.DoesFileExist(storageName, roomsFile, true) {
+		return jr.Rooms, nil
+	}
+	err := rl.sm.DecodeFile(storageName, roomsFile, storage.TOML, true, &jr)
+	if err != nil {
+		return jr.Rooms, errors.New("Error loading rooms: " + err.Error())
+	}
+	return jr.Rooms, nil
+}
+
+// save has to be called with the lock held
+func (rl *roomList) save() error {
+	var jr joinedRooms
+	for r := range rl.joined {
+		jr.Rooms = append(jr.Rooms, r.String())
+	}
+	sort.Strings(jr.Rooms)
+	return rl.sm.EncodeFile(storageName, roomsFile, storage.TOML, true, jr)
+}
+
+func (rl *roomList) joinAll(configured []string) error {
+	stored, err := rl.load()
+	if err != nil {
+		rl.logger.Warnw("Unable to load joined rooms", "error", err)
+	}
+	for _, r := range configured {
+		_, err := rl.join(r, false)
+		if err != nil {
+			return errors.New("Error joing Room " + r + ": " + err.Error())
+		}
+	}
+	for _, r := range stored {
+		if rl.contains(id.RoomID(r)) {
+			// Older versions stored the configured rooms as well
+			continue
+		}
+		_, err := rl.join(r, true)
+		if err != nil {
+			rl.logger.Warnw("Unable to rejoin room", "room", r, "error", err)
+		}
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.save()
+}
+
+func (rl *roomList) contains(roomID id.RoomID) bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.rooms[roomID]
+}
+
+func (rl *roomList) JoinRoom(roomIDorAlias string) (id.RoomID, error) {
+	return rl.join(roomIDorAlias, true)
+}
+
+// join joins the room, runtime joins are persisted
+func (rl *roomList) join(roomIDorAlias string, runtime bool) (id.RoomID, error) {
+	resp, err := rl.client.JoinRoom(roomIDorAlias, "", nil)
+	if err != nil {
+		return "", err
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rooms[resp.RoomID] = true
+	if !runtime {
+		return resp.RoomID, nil
+	}
+	rl.joined[resp.RoomID] = true
+	return resp.RoomID, rl.save()
+}
+
+func (rl *roomList) LeaveRoom(roomID id.RoomID) error {
+	_, err := rl.client.LeaveRoom(roomID)
+	if err != nil {
+		return err
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.rooms, roomID)
+	delete(rl.joined, roomID)
+	return rl.save()
+}
+
+func (rl *roomList) JoinedRooms() []id.RoomID {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	var result []id.RoomID
+	for r := range rl.rooms {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// isAllowedInviter accepts entries of the form @user:server, :server or server
+func isAllowedInviter(sender id.UserID, allowlist []string) bool {
+	_, server, err := sender.Parse()
+	if err != nil {
+		return false
+	}
+	for _, a := range allowlist {
+		if a == sender.String() || strings.TrimPrefix(a, ":") == server {
+			return true
+		}
+	}
+	return false
+}
+
+func (rl *roomList) handleInvite(allowlist []string) mautrix.EventHandler {
+	return func(source mautrix.EventSource, evt *event.Event) {
+		m := evt.Content.AsMember()
+		if m.Membership != event.MembershipInvite || evt.GetStateKey() != rl.client.UserID.String() {
+			return
+		}
+		if rl.contains(evt.RoomID) {
+			return
+		}
+		if !isAllowedInviter(evt.Sender, allowlist) {
+			rl.logger.Infow("Ignoring invite", "room", evt.RoomID, "sender", evt.Sender)
+			return
+		}
+		rl.logger.Infow("Accepting invite", "room", evt.RoomID, "sender", evt.Sender)
+		_, err := rl.JoinRoom(evt.RoomID.String())
+		if err != nil {
+			rl.logger.Errorw("Unable to join room", "room", evt.RoomID, "error", err)
+		}
+	}
+}
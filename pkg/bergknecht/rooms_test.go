@@ -0,0 +1,111 @@
+package bergknecht
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+)
+
+// fakeRoomServer joins every room, aliases resolve to the room ID with the
+// same name
+type fakeRoomServer struct {
+	mu     sync.Mutex
+	joins  []string
+	leaves []string
+}
+
+func (rs *fakeRoomServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	path, _ := url.PathUnescape(r.URL.EscapedPath())
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	switch {
+	case strings.Contains(path, "/join/"):
+		room := path[strings.Index(path, "/join/")+len("/join/"):]
+		rs.joins = append(rs.joins, room)
+		json.NewEncoder(w).Encode(map[string]string{"room_id": "!" + strings.TrimLeft(room, "#!")})
+	case strings.HasSuffix(path, "/leave"):
+		rs.leaves = append(rs.leaves, path)
+		w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func storedRooms(t *testing.T, sm *storage.Manager) []string {
+	t.Helper()
+	var jr joinedRooms
+	err := sm.DecodeFile(storageName, roomsFile, storage.TOML, true, &jr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jr.Rooms
+}
+
+func TestRoomListPersistsRuntimeJoins(t *testing.T) {
+	rs := &fakeRoomServer{}
+	srv := httptest.NewServer(rs)
+	defer srv.Close()
+	client, err := mautrix.NewClient(srv.URL, "@bot:test", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := storage.CreateStorageManager(storage.Config{CachedPath: t.TempDir(), PersistentPath: t.TempDir()})
+	start := func(configured ...string) *roomList {
+		rl := newRoomList(client, sm, zap.NewNop().Sugar())
+		err := rl.joinAll(configured)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rl
+	}
+
+	rl := start("#konfiguriert:test")
+	_, err = rl.JoinRoom("!eingeladen:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := storedRooms(t, sm); !reflect.DeepEqual(got, []string{"!eingeladen:test"}) {
+		t.Errorf("stored rooms = %v, want only the one joined at runtime", got)
+	}
+
+	// The room removed from the config is not joined again
+	rl = start()
+	if got := rl.JoinedRooms(); !reflect.DeepEqual(got, []id.RoomID{"!eingeladen:test"}) {
+		t.Errorf("rooms after removing the configured one = %v", got)
+	}
+
+	// Files of older versions also list the configured rooms
+	err = sm.EncodeFile(storageName, roomsFile, storage.TOML, true, joinedRooms{Rooms: []string{"!eingeladen:test", "!konfiguriert:test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl = start("#konfiguriert:test")
+	if got := storedRooms(t, sm); !reflect.DeepEqual(got, []string{"!eingeladen:test"}) {
+		t.Errorf("stored rooms after the start = %v, want the configured one dropped", got)
+	}
+	if got := rl.JoinedRooms(); len(got) != 2 {
+		t.Errorf("rooms = %v, want the configured and the runtime one", got)
+	}
+
+	err = rl.LeaveRoom("!eingeladen:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := storedRooms(t, sm); len(got) != 0 {
+		t.Errorf("stored rooms after leaving = %v, want none", got)
+	}
+	if len(rs.leaves) != 1 {
+		t.Errorf("left %v rooms at the homeserver, want 1", len(rs.leaves))
+	}
+}
@@ -0,0 +1,71 @@
+package bergknecht
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+)
+
+const storageName = "Bergknecht"
+const sessionFile = "session.toml"
+
+type session struct {
+	Homeserver  string
+	UserID      string
+	DeviceID    string
+	AccessToken string
+}
+
+func useAccessToken(client *mautrix.Client, token, deviceID string) error {
+	client.AccessToken = token
+	resp, err := client.Whoami()
+	if err != nil {
+		client.AccessToken = ""
+		return err
+	}
+	client.UserID = resp.UserID
+	client.DeviceID = resp.DeviceID
+	if client.DeviceID == "" {
+		client.DeviceID = id.DeviceID(deviceID)
+	}
+	return nil
+}
+
+func restoreSession(client *mautrix.Client, sm *storage.Manager) (bool, error) {
+	if !sm.DoesFileExist(storageName, sessionFile, true) {
+		return false, nil
+	}
+	var s session
+	err := sm.DecodeFile(storageName, sessionFile, storage.TOML, true, &s)
+	if err != nil {
+		return false, errors.New("Error loading session: " + err.Error())
+	}
+	if s.Homeserver != "" {
+		hs, err := url.Parse(s.Homeserver)
+		if err != nil {
+			return false, errors.New("Error parsing stored homeserver: " + err.Error())
+		}
+		client.HomeserverURL = hs
+	}
+	err = useAccessToken(client, s.AccessToken, s.DeviceID)
+	if err != nil {
+		if errors.Is(err, mautrix.MUnknownToken) {
+			sm.DeleteFile(storageName, sessionFile, true)
+		}
+		return false, errors.New("Error using stored session: " + err.Error())
+	}
+	return true, nil
+}
+
+func saveSession(client *mautrix.Client, sm *storage.Manager) error {
+	s := session{
+		Homeserver:  client.HomeserverURL.String(),
+		UserID:      client.UserID.String(),
+		DeviceID:    client.DeviceID.String(),
+		AccessToken: client.AccessToken,
+	}
+	return sm.EncodeFile(storageName, sessionFile, storage.TOML, true, s)
+}
@@ -0,0 +1,145 @@
+package bergknecht
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/config"
+	"github.com/Nerdbergev/Bergknecht/pkg/metrics"
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+)
+
+const defaultReconnectMin = time.Second
+const defaultReconnectMax = 5 * time.Minute
+
+// failingSyncer hands every failed sync back to the caller of Sync instead of
+// retrying internally, the supervisor decides how long to wait.
+type failingSyncer struct {
+	*mautrix.DefaultSyncer
+}
+
+func (s failingSyncer) OnFailedSync(res *mautrix.RespSync, err error) (time.Duration, error) {
+	return 0, err
+}
+
+type supervisor struct {
+	client   *mautrix.Client
+	conf     config.Config
+	sm       *storage.Manager
+	logger   *zap.SugaredLogger
+	min      time.Duration
+	max      time.Duration
+	attempts int32
+}
+
+func newSupervisor(client *mautrix.Client, conf config.Config, sm *storage.Manager, logger *zap.SugaredLogger) *supervisor {
+	res := new(supervisor)
+	res.client = client
+	res.conf = conf
+	res.sm = sm
+	res.logger = logger
+	res.min = time.Duration(conf.SyncSettings.ReconnectMinSeconds) * time.Second
+	if res.min <= 0 {
+		res.min = defaultReconnectMin
+	}
+	res.max = time.Duration(conf.SyncSettings.ReconnectMaxSeconds) * time.Second
+	if res.max < res.min {
+		res.max = defaultReconnectMax
+	}
+
+	syncer := client.Syncer.(*mautrix.DefaultSyncer)
+	syncer.OnSync(res.onSync)
+	client.Syncer = failingSyncer{syncer}
+	return res
+}
+
+func (s *supervisor) onSync(resp *mautrix.RespSync, since string) bool {
+	metrics.SyncSucceeded()
+	old := atomic.SwapInt32(&s.attempts, 0)
+	if old > 0 {
+		s.logger.Infow("Sync recovered", "attempts", old)
+	}
+	return true
+}
+
+func reconnectDelay(attempt int, min, max time.Duration) time.Duration {
+	d := min
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+// canRelogin returns why a rejected token cannot be replaced by a password
+// login, retrying would fail forever
+func (s *supervisor) canRelogin() error {
+	if s.conf.Serversettings.AccessToken != "" {
+		return errors.New("configured access token was rejected")
+	}
+	if s.conf.Serversettings.Password == "" {
+		return errors.New("stored session was rejected and no password is configured")
+	}
+	return nil
+}
+
+func (s *supervisor) relogin() error {
+	err := passwordLogin(s.client, s.conf, s.client.DeviceID.String())
+	if err != nil {
+		return err
+	}
+	if s.conf.Serversettings.PersistSession {
+		err = saveSession(s.client, s.sm)
+		if err != nil {
+			s.logger.Errorw("Unable to save session", "error", err)
+		}
+	}
+	return nil
+}
+
+// run syncs until ctx is cancelled. Failed syncs are retried with exponential
+// backoff, a rejected token leads to a new password login.
+func (s *supervisor) run(ctx context.Context) error {
+	for {
+		err := s.client.SyncWithContext(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			return nil
+		}
+		metrics.SyncFailed(err)
+		attempt := int(atomic.AddInt32(&s.attempts, 1))
+		max := s.conf.SyncSettings.MaxReconnects
+		if max > 0 && attempt > max {
+			return errors.New("giving up after " + strconv.Itoa(max) + " reconnects: " + err.Error())
+		}
+
+		if errors.Is(err, mautrix.MUnknownToken) {
+			lerr := s.canRelogin()
+			if lerr != nil {
+				return errors.New("Error relogging in: " + lerr.Error())
+			}
+			s.logger.Warnw("Access token rejected, logging in again", "attempt", attempt)
+			lerr = s.relogin()
+			if lerr != nil {
+				s.logger.Errorw("Relogin failed", "attempt", attempt, "error", lerr)
+			}
+		}
+
+		delay := reconnectDelay(attempt, s.min, s.max)
+		s.logger.Warnw("Sync failed, reconnecting", "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
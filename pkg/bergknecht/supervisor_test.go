@@ -0,0 +1,128 @@
+package bergknecht
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/config"
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+)
+
+// fakeHomeserver rejects every token but the one handed out by its login
+type fakeHomeserver struct {
+	password string
+	logins   int32
+	syncs    int32
+}
+
+func (hs *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/login"):
+		var req struct{ Password string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Password != hs.password {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"Invalid password"}`))
+			return
+		}
+		atomic.AddInt32(&hs.logins, 1)
+		w.Write([]byte(`{"access_token":"new","device_id":"DEVICE","user_id":"@bot:test"}`))
+	case r.Header.Get("Authorization") != "Bearer new":
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`))
+	case strings.HasSuffix(r.URL.Path, "/filter"):
+		w.Write([]byte(`{"filter_id":"1"}`))
+	case strings.HasSuffix(r.URL.Path, "/sync"):
+		atomic.AddInt32(&hs.syncs, 1)
+		w.Write([]byte(`{"next_batch":"s1"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSupervisorRelogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		accessToken string
+		wantErr     string
+		wantLogins  int32
+	}{
+		{name: "password login", password: "geheim", wantLogins: 1},
+		{name: "no password", wantErr: "no password is configured"},
+		{name: "configured token", password: "geheim", accessToken: "old", wantErr: "configured access token was rejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := &fakeHomeserver{password: "geheim"}
+			srv := httptest.NewServer(hs)
+			defer srv.Close()
+
+			client, err := mautrix.NewClient(srv.URL, "@bot:test", "old")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var conf config.Config
+			conf.Serversettings.Username = "bot"
+			conf.Serversettings.Password = tt.password
+			conf.Serversettings.AccessToken = tt.accessToken
+			conf.SyncSettings.MaxReconnects = 5
+			sm := storage.CreateStorageManager(storage.Config{CachedPath: t.TempDir(), PersistentPath: t.TempDir()})
+			s := newSupervisor(client, conf, sm, zap.NewNop().Sugar())
+			s.min, s.max = time.Millisecond, time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			client.Syncer.(failingSyncer).OnSync(func(resp *mautrix.RespSync, since string) bool {
+				cancel()
+				return true
+			})
+
+			err = s.run(ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("run() = %v, want error containing %q", err, tt.wantErr)
+				}
+			} else {
+				if err != context.Canceled {
+					t.Fatalf("run() = %v, want context.Canceled after the sync resumed", err)
+				}
+				if atomic.LoadInt32(&hs.syncs) == 0 {
+					t.Error("no successful sync after the relogin")
+				}
+				if client.AccessToken != "new" {
+					t.Errorf("access token = %q, want the one of the new login", client.AccessToken)
+				}
+			}
+			if got := atomic.LoadInt32(&hs.logins); got != tt.wantLogins {
+				t.Errorf("logins = %v, want %v", got, tt.wantLogins)
+			}
+		})
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := reconnectDelay(tt.attempt, time.Second, time.Minute); got != tt.want {
+			t.Errorf("reconnectDelay(%v) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bergknecht
+
+import (
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+)
+
+const syncFile = "sync.toml"
+
+type syncState struct {
+	UserID    string
+	FilterID  string
+	NextBatch string
+}
+
+// syncStore keeps the sync token and filter in the persistent storage so a
+// restarted bot continues where it stopped, rooms are only kept in memory.
+type syncStore struct {
+	*mautrix.InMemoryStore
+	sm     *storage.Manager
+	logger *zap.SugaredLogger
+	state  syncState
+}
+
+func newSyncStore(sm *storage.Manager, logger *zap.SugaredLogger) *syncStore {
+	res := new(syncStore)
+	res.InMemoryStore = mautrix.NewInMemoryStore()
+	res.sm = sm
+	res.logger = logger
+	if sm.DoesFileExist(storageName, syncFile, true) {
+		err := sm.DecodeFile(storageName, syncFile, storage.TOML, true, &res.state)
+		if err != nil {
+			logger.Warnw("Unable to load sync state, starting without", "error", err)
+			res.state = syncState{}
+		}
+	}
+	return res
+}
+
+func (s *syncStore) save() {
+	err := s.sm.EncodeFile(storageName, syncFile, storage.TOML, true, s.state)
+	if err != nil {
+		s.logger.Errorw("Unable to save sync state", "error", err)
+	}
+}
+
+func (s *syncStore) SaveFilterID(userID id.UserID, filterID string) {
+	s.state.UserID = userID.String()
+	s.state.FilterID = filterID
+	s.save()
+}
+
+func (s *syncStore) LoadFilterID(userID id.UserID) string {
+	if s.state.UserID != userID.String() {
+		return ""
+	}
+	return s.state.FilterID
+}
+
+func (s *syncStore) SaveNextBatch(userID id.UserID, nextBatchToken string) {
+	if s.state.UserID != userID.String() {
+		s.state = syncState{UserID: userID.String()}
+	}
+	s.state.NextBatch = nextBatchToken
+	s.save()
+}
+
+func (s *syncStore) LoadNextBatch(userID id.UserID) string {
+	if s.state.UserID != userID.String() {
+		return ""
+	}
+	return s.state.NextBatch
+}
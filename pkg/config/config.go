@@ -4,22 +4,55 @@ import (
 	"errors"
 	"os"
 
+	"github.com/Nerdbergev/Bergknecht/pkg/bergcrypto"
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/metrics"
 	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"github.com/Nerdbergev/Bergknecht/pkg/webhook"
 	"github.com/pelletier/go-toml"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	Serversettings  serverSettings
-	LoggerSettings  zap.Config
-	StorageSettings storage.Config
+	Serversettings   serverSettings
+	LoggerSettings   zap.Config
+	StorageSettings  storage.Config
+	CryptoSettings   bergcrypto.Config
+	SyncSettings     syncSettings
+	DispatchSettings berghandler.DispatchConfig
+	OutboundSettings berghandler.OutboundConfig
+	MetricsSettings  metrics.Config
+	WebhookSettings  webhook.Config
+	Permissions      berghandler.PermissionConfig
+	RateLimits       berghandler.RateLimitConfig
+	Handlers         handlerSettings
+	RoomDefaults     berghandler.RoomSettings
+	RoomSettings     map[string]berghandler.RoomSettings //Per room ID, overrides RoomDefaults
 }
 
 type serverSettings struct {
-	Homserver string
-	Username  string
-	Password  string
-	Rooms     []string
+	Homserver       string
+	Username        string
+	Password        string
+	AccessToken     string //Optional, used instead of the password login
+	DeviceID        string //Optional, device of the AccessToken or device to log in as
+	PersistSession  bool   //Store token and device of the password login and reuse them on restart
+	Rooms           []string
+	InviteAllowlist []string //Users (@user:server) or servers whose invites are accepted
+	Admins          []string //Admins in every room, same as Permissions.Admins
+}
+
+type syncSettings struct {
+	CatchUpMinutes      int //Process missed commands up to this age after a restart, 0 only new ones, -1 all
+	ReconnectMinSeconds int //First backoff after a failed sync, doubled on every further failure
+	ReconnectMaxSeconds int //Upper limit of the backoff
+	MaxReconnects       int //Give up after this many failed syncs in a row, 0 never
+}
+
+type handlerSettings struct {
+	Enabled   []string                              //Handler names in the order they get the events
+	Rooms     map[string][]string                   //Optional room restriction per Handler name
+	Responses map[string]berghandler.ResponseConfig //How each Handler answers, per Handler name
 }
 
 func LoadConfig(filepath string) (Config, error) {
@@ -34,5 +67,9 @@ func LoadConfig(filepath string) (Config, error) {
 	if err != nil {
 		return res, errors.New("Error decoding file: " + err.Error())
 	}
+	_, err = berghandler.ParseRole(res.Permissions.DefaultRole)
+	if err != nil {
+		return res, errors.New("Error in Permissions: " + err.Error())
+	}
 	return res, nil
 }
@@ -5,12 +5,10 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,23 +25,54 @@ type BestellungHandler struct {
 	subHandlers   berghandler.SubHandlers
 }
 
+func init() {
+	berghandler.RegisterHandler(&BestellungHandler{})
+}
+
 func (h *BestellungHandler) Prime(he berghandler.HandlerEssentials) error {
 	h.subHandlers = make(map[string]berghandler.SubHandlerSet)
-	h.subHandlers["new"] = berghandler.SubHandlerSet{F: h.newOrder, H: "Erstellt eine Neue Bestellung.", U: "new $Lieferdienst", NV: 1, OV: 0}
-	h.subHandlers["add"] = berghandler.SubHandlerSet{F: h.addtoOrder, H: "Hinzufügen eines Items zur Bestellung", U: "add $Bestellung $Artikel [$Version $Extras $Kommentar $Anzahl]", NV: 2, OV: 4}
-	h.subHandlers["show"] = berghandler.SubHandlerSet{F: h.printOrder, H: "Anzeigen einer Bestellung", U: "show $Bestellung", NV: 1, OV: 0}
-	h.subHandlers["call-text"] = berghandler.SubHandlerSet{F: h.getCallText, H: "Ausgabe einen Textes zum Anrufen", U: "call-text $Bestellung", NV: 1, OV: 0}
-	h.subHandlers["print-payment"] = berghandler.SubHandlerSet{F: h.printPayment, H: "Ausgabe der Informationen wer was bezahlen muss", U: "print-payment $Bestellung [$Gezahlt]", NV: 1, OV: 1}
-	h.subHandlers["get-total"] = berghandler.SubHandlerSet{F: h.getTotal, H: "Ausgabe wie viel die Bestellung kostet plus Trinkgeld Vorschläge", U: "get-total $Bestellung", NV: 1, OV: 0}
-	h.subHandlers["remove"] = berghandler.SubHandlerSet{F: h.deletePosition, H: "Löscht Position aus der Bestellung", U: "remove $Bestellung $Position", NV: 2, OV: 0}
-	h.subHandlers["close"] = berghandler.SubHandlerSet{F: h.removeOrder, H: "Schließt Bestellung und Löscht diese", U: "close $Bestellung", NV: 1, OV: 0}
-	h.subHandlers["add-strichliste"] = berghandler.SubHandlerSet{F: h.addStrichliste, H: "Verknüpft den schreibenden Matrix account mit einem Strichlisten Benutzer", U: "add-strichliste $Benutzername", NV: 1, OV: 0}
-	h.subHandlers["remove-strichliste"] = berghandler.SubHandlerSet{F: h.removeStrichliste, H: "Löscht Matrix account zu Strichlisten account verknüpfung", U: "remove-strichliste", NV: 0, OV: 0}
-	h.subHandlers["process-strichliste"] = berghandler.SubHandlerSet{F: h.processStrichliste, H: "Versucht Bestellung via Strichliste abzurechenen", U: "process-strichliste $Bestellung [$Bezahlendendes-Wesen]", NV: 1, OV: 1}
-	h.subHandlers["menu"] = berghandler.SubHandlerSet{F: h.showMenu, H: "Zeigt Menü eines Lieferdienstes", U: "menu $Lieferdienst", NV: 1, OV: 0}
-	h.subHandlers["article"] = berghandler.SubHandlerSet{F: h.showArticle, H: "Zeigt Artikelinformationen", U: "article $Lieferdienst $Article", NV: 2, OV: 0}
-	h.subHandlers["restaurants"] = berghandler.SubHandlerSet{F: h.showRestaurants, H: "Zeigt alle Lieferdienste", U: "restaurants", NV: 0, OV: 0}
-
+	order := berghandler.Param{Name: "Bestellung", Type: berghandler.ParamOrder}
+	lieferdienst := berghandler.Param{Name: "Lieferdienst", Type: berghandler.ParamString}
+	h.subHandlers["new"] = berghandler.SubHandlerSet{A: h.newOrder, R: berghandler.RoleMember, H: "bestellung.help.new", C: catOrder, E: []string{"pizzeria"}, P: []berghandler.Param{
+		{Name: "Lieferdienst", Type: berghandler.ParamString, Optional: true},
+	}}
+	h.subHandlers["add"] = berghandler.SubHandlerSet{A: h.addtoOrder, R: berghandler.RoleMember, H: "bestellung.help.add", C: catOrder, E: []string{"drei-rote-affen margherita gross", "drei-rote-affen margherita anzahl=2 kommentar=\"ohne Zwiebeln\""}, P: []berghandler.Param{
+		order,
+		{Name: "Artikel", Type: berghandler.ParamString},
+		{Name: "Version", Type: berghandler.ParamString, Optional: true},
+		{Name: "Extras", Type: berghandler.ParamString, Optional: true},
+		{Name: "Kommentar", Type: berghandler.ParamString, Optional: true, RawCase: true},
+		{Name: "Anzahl", Type: berghandler.ParamInt, Optional: true},
+	}}
+	h.subHandlers["show"] = berghandler.SubHandlerSet{A: h.printOrder, H: "bestellung.help.show", C: catOrder, E: []string{"drei-rote-affen"}, P: []berghandler.Param{order}}
+	h.subHandlers["call-text"] = berghandler.SubHandlerSet{A: h.getCallText, H: "bestellung.help.call-text", C: catOrder, E: []string{"drei-rote-affen"}, P: []berghandler.Param{order}}
+	h.subHandlers["print-payment"] = berghandler.SubHandlerSet{A: h.printPayment, R: berghandler.RoleAdmin, O: h.ownsOrder, H: "bestellung.help.print-payment", C: catPayment, E: []string{"drei-rote-affen", "drei-rote-affen 42,50"}, P: []berghandler.Param{
+		order,
+		{Name: "Gezahlt", Type: berghandler.ParamMoney, Optional: true},
+	}}
+	h.subHandlers["get-total"] = berghandler.SubHandlerSet{A: h.getTotal, H: "bestellung.help.get-total", C: catPayment, E: []string{"drei-rote-affen"}, P: []berghandler.Param{order}}
+	h.subHandlers["remove"] = berghandler.SubHandlerSet{A: h.deletePosition, R: berghandler.RoleAdmin, O: h.ownsPosition, H: "bestellung.help.remove", C: catOrder, E: []string{"drei-rote-affen 0"}, P: []berghandler.Param{
+		order,
+		{Name: "Position", Type: berghandler.ParamInt},
+	}}
+	h.subHandlers["close"] = berghandler.SubHandlerSet{A: h.removeOrder, R: berghandler.RoleAdmin, O: h.ownsOrder, H: "bestellung.help.close", C: catOrder, E: []string{"drei-rote-affen"}, P: []berghandler.Param{order}}
+	h.subHandlers["add-strichliste"] = berghandler.SubHandlerSet{A: h.addStrichliste, R: berghandler.RoleMember, H: "bestellung.help.add-strichliste", C: catStrichliste, E: []string{"Max"}, P: []berghandler.Param{
+		{Name: "Benutzername", Type: berghandler.ParamString, RawCase: true},
+	}}
+	h.subHandlers["remove-strichliste"] = berghandler.SubHandlerSet{A: h.removeStrichliste, R: berghandler.RoleMember, H: "bestellung.help.remove-strichliste", C: catStrichliste}
+	h.subHandlers["process-strichliste"] = berghandler.SubHandlerSet{A: h.processStrichliste, R: berghandler.RoleAdmin, O: h.ownsOrder, H: "bestellung.help.process-strichliste", C: catStrichliste, E: []string{"drei-rote-affen"}, P: []berghandler.Param{
+		order,
+		{Name: "Bezahlendes-Wesen", Type: berghandler.ParamUser, Optional: true},
+	}}
+	h.subHandlers["menu"] = berghandler.SubHandlerSet{A: h.showMenu, H: "bestellung.help.menu", C: catRestaurants, E: []string{"pizzeria"}, P: []berghandler.Param{lieferdienst}}
+	h.subHandlers["article"] = berghandler.SubHandlerSet{A: h.showArticle, H: "bestellung.help.article", C: catRestaurants, E: []string{"pizzeria margherita"}, P: []berghandler.Param{
+		lieferdienst,
+		{Name: "Artikel", Type: berghandler.ParamString},
+	}}
+	h.subHandlers["restaurants"] = berghandler.SubHandlerSet{A: h.showRestaurants, H: "bestellung.help.restaurants", C: catRestaurants}
+
+	migrateCachedOrders(he)
+	countOrders(he)
 	return he.Storage.DecodeFile(handlerName, "lieferdienste.toml", storage.TOML, true, h)
 }
 
@@ -55,6 +84,14 @@ func (h *BestellungHandler) GetCommand() string {
 	return command
 }
 
+func (h *BestellungHandler) GetDescription() string {
+	return "bestellung.description"
+}
+
+func (h *BestellungHandler) HasSubCommand(sub string) bool {
+	return h.subHandlers.Has(sub)
+}
+
 func (h *BestellungHandler) Handle(he berghandler.HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
 	return h.subHandlers.Handle(command, handlerName, he, evt)
 }
@@ -72,15 +109,33 @@ func (h *BestellungHandler) searchLieferdienst(ld string) (bool, LieferDienst) {
 	return found, res
 }
 
-func (h *BestellungHandler) newOrder(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var ld string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &ld)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
+func (h *BestellungHandler) lieferdienstNotFound(he berghandler.HandlerEssentials, ld string) string {
+	var names []string
+	for _, l := range h.Lieferdienste {
+		names = append(names, strings.ToLower(l.Name))
+	}
+	return berghandler.Tf(he, "bestellung.restaurant.notfound", he.CommandPrefix()+command) + berghandler.DidYouMean(he, ld, names)
+}
+
+func articleNotFound(he berghandler.HandlerEssentials, ld LieferDienst, artikel string) string {
+	var names []string
+	for _, a := range ld.Artikel {
+		names = append(names, strings.ToLower(a.Name))
+	}
+	return berghandler.Tf(he, "bestellung.article.notfound", he.CommandPrefix()+command, strings.ToLower(ld.Name)) + berghandler.DidYouMean(he, artikel, names)
+}
+
+func (h *BestellungHandler) newOrder(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	ld := args.String("Lieferdienst")
+	if ld == "" {
+		ld = strings.ToLower(he.Room.DefaultRestaurant)
+	}
+	if ld == "" {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.restaurant.missing"))
 	}
 	found, l := h.searchLieferdienst(ld)
 	if !found {
-		return berghandler.SendMessage(he, evt, handlerName, "Lieferdienst nicht gefunden, benutze !bestellung dienste für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, h.lieferdienstNotFound(he, ld))
 	}
 	z := getRandomWord(zahlen)
 	a := getRandomWord(adjektive)
@@ -93,12 +148,22 @@ func (h *BestellungHandler) newOrder(he berghandler.HandlerEssentials, evt *even
 	be.Ersteller = User{evt.Sender.Localpart(), evt.Sender.String()}
 	be.LieferDienst = ld
 	be.Nummer = l.Telefonnummer
-	err = he.Storage.EncodeFile(handlerName, bnf, storage.TOML, false, be)
+	err := he.Storage.EncodeFile(orderStorage, bnf, storage.TOML, true, be)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler bei erstellung der Bestellung")
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.order.createerror"))
+	}
+	countOrders(he)
+	// The status is posted once the order exists, a status that is not saved
+	// would never be updated or unpinned
+	if h.postStatus(he, &be, evt) {
+		err = he.Storage.EncodeFile(orderStorage, bnf, storage.TOML, true, be)
+		if err != nil {
+			he.Logger.Warnw("Unable to save status", "Handler", handlerName, "Error", err)
+			be.Status.Unpin(he)
+		}
 	}
 
-	return berghandler.SendMessage(he, evt, handlerName, "Neue Bestellung mit dem Name: "+bn+" erstellt")
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.created", bn))
 }
 
 func parseExtras(extras string, artikel Artikel) ([]Zusatz, error) {
@@ -110,7 +175,7 @@ func parseExtras(extras string, artikel Artikel) ([]Zusatz, error) {
 	r.Comma = ','
 	read, err := r.Read()
 	if err != nil {
-		return result, errors.New("Fehler beim lesen der Extras: " + err.Error())
+		return result, berghandler.NewMessageError("bestellung.extras.read", err.Error())
 	}
 	for _, r := range read {
 		cont := false
@@ -122,7 +187,7 @@ func parseExtras(extras string, artikel Artikel) ([]Zusatz, error) {
 			}
 		}
 		if !cont {
-			return result, errors.New("Konnte Zusatz " + r + " nicht zuordnen")
+			return result, berghandler.NewMessageError("bestellung.extras.unknown", r)
 		}
 	}
 	return result, nil
@@ -136,27 +201,23 @@ func getExtrasTotal(zusätze []Zusatz) float64 {
 	return result
 }
 
-func (h *BestellungHandler) addtoOrder(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order, artikel, version, extras, kommentar, anzahl string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order, &artikel, &version, &extras, &kommentar, &anzahl)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) addtoOrder(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
+	artikel := args.String("Artikel")
+	version := args.String("Version")
+	extras := args.String("Extras")
+	kommentar := args.String("Kommentar")
 	amount := 1
-	if anzahl != "" {
-		a, err := strconv.Atoi(anzahl)
-		if err != nil {
-			return berghandler.SendMessage(he, evt, handlerName, "Menge konnte nicht konvertiert werden: "+err.Error())
-		}
-		amount = a
+	if args.Has("Anzahl") {
+		amount = args.Int("Anzahl")
 	}
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
 	ex, ld := h.searchLieferdienst(be.LieferDienst)
 	if !ex {
-		return berghandler.SendMessage(he, evt, handlerName, "Lieferdienst nicht gefunden, benutze !bestellung dienste für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, h.lieferdienstNotFound(he, be.LieferDienst))
 	}
 	ex = false
 	var desiredArtikel Artikel
@@ -168,7 +229,7 @@ func (h *BestellungHandler) addtoOrder(he berghandler.HandlerEssentials, evt *ev
 		}
 	}
 	if !ex {
-		return berghandler.SendMessage(he, evt, handlerName, "Artikel nicht gefunden, benutze !bestellung article $Lieferdienst für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, articleNotFound(he, ld, artikel))
 	}
 	desiredVersion := desiredArtikel.Versionen[0]
 	if len(desiredArtikel.Versionen) > 1 {
@@ -183,7 +244,7 @@ func (h *BestellungHandler) addtoOrder(he berghandler.HandlerEssentials, evt *ev
 	}
 	desiredExtras, err := parseExtras(extras, desiredArtikel)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim parsen der extras:"+err.Error()+" Benutze !bestellung article $Lieferdienst für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.extras.error", berghandler.TError(he, err), he.CommandPrefix()+command, strings.ToLower(ld.Name), artikel))
 	}
 
 	orderedby := User{evt.Sender.Localpart(), evt.Sender.String()}
@@ -198,166 +259,232 @@ func (h *BestellungHandler) addtoOrder(he berghandler.HandlerEssentials, evt *ev
 	posi.Kommentar = kommentar
 	be.Positionen = append(be.Positionen, posi)
 	be.calcTotal()
-	err = he.Storage.EncodeFile(handlerName, order+".toml", storage.TOML, false, be)
+	err = he.Storage.EncodeFile(orderStorage, order+".toml", storage.TOML, true, be)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Speichern der bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.saveerror", err.Error()))
 	}
-	return berghandler.SendMessage(he, evt, handlerName, "Artikel hinzugefügt")
+	updateStatus(he, be, "")
+	return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.article.added"))
 }
 
 func (h *BestellungHandler) loadOrder(he berghandler.HandlerEssentials, order string) (Bestellung, error) {
 	be := Bestellung{}
-	ex := he.Storage.DoesFileExist(handlerName, order+".toml", false)
+	ex := he.Storage.DoesFileExist(orderStorage, order+".toml", true)
 	if !ex {
-		return be, errors.New("Bestellung nicht vorhanden")
+		return be, berghandler.NewMessageError("bestellung.order.notfound")
 	}
-	err := he.Storage.DecodeFile(handlerName, order+".toml", storage.TOML, false, &be)
+	err := he.Storage.DecodeFile(orderStorage, order+".toml", storage.TOML, true, &be)
 	if err != nil {
-		return be, errors.New("Fehler beim Laden der bestellung: " + err.Error())
+		return be, berghandler.NewMessageError("bestellung.order.decode", err.Error())
 	}
 	return be, nil
 }
 
-func (h *BestellungHandler) printOrder(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order)
+// ownsOrder lets the creator close and pay the order and set what was paid,
+// an order that does not load is left to the command to report
+func (h *BestellungHandler) ownsOrder(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	be, err := h.loadOrder(he, args.String("Bestellung"))
+	return err != nil || be.isCreator(evt.Sender.String())
+}
+
+// ownsPosition lets the creator of the order and the ones who ordered the
+// position remove it
+func (h *BestellungHandler) ownsPosition(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	be, err := h.loadOrder(he, args.String("Bestellung"))
+	posi := args.Int("Position")
+	if err != nil || posi < 0 || posi >= len(be.Positionen) {
+		return true
+	}
+	return be.isCreator(evt.Sender.String()) || be.Positionen[posi].isBesteller(evt.Sender.String())
+}
+
+// migrateCachedOrders moves the orders of older versions, which kept them in
+// the cache, to the persistent storage
+func migrateCachedOrders(he berghandler.HandlerEssentials) {
+	files, err := he.Storage.ListFiles(handlerName, false)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
+		he.Logger.Warnw("Unable to list cached orders", "Handler", handlerName, "Error", err)
+		return
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".toml") {
+			continue
+		}
+		var be Bestellung
+		err = he.Storage.DecodeFile(handlerName, f, storage.TOML, false, &be)
+		if err == nil {
+			err = he.Storage.EncodeFile(orderStorage, f, storage.TOML, true, be)
+		}
+		if err == nil {
+			err = he.Storage.DeleteFile(handlerName, f, false)
+		}
+		if err != nil {
+			he.Logger.Warnw("Unable to migrate cached order", "Handler", handlerName, "Order", f, "Error", err)
+		}
 	}
+}
+
+func (h *BestellungHandler) printOrder(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
+	}
+	if be.Status.RoomID == evt.RoomID {
+		updateStatus(he, be, "")
+		return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.status.updated"))
+	}
+	if !be.Status.IsSet() && h.postStatus(he, &be, evt) {
+		err = he.Storage.EncodeFile(orderStorage, order+".toml", storage.TOML, true, be)
+		if err != nil {
+			be.Status.Unpin(he)
+			return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.saveerror", err.Error()))
+		}
+		return true
 	}
-	msg := be.prettyFormat()
+	msg := be.prettyFormat(he)
 	return berghandler.SendFormattedMessage(he, evt, handlerName, msg)
 }
 
-func (h *BestellungHandler) getCallText(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order)
+// statusEssentials drops the language of the sender, the status message is
+// shared by everyone in the room.
+func statusEssentials(he berghandler.HandlerEssentials) berghandler.HandlerEssentials {
+	he.UserLanguage = ""
+	return he
+}
+
+// postStatus posts and pins the status message of be in the room of evt,
+// the caller has to save be afterwards.
+func (h *BestellungHandler) postStatus(he berghandler.HandlerEssentials, be *Bestellung, evt *event.Event) bool {
+	lm, err := berghandler.PostLiveMessage(he, evt.RoomID, be.prettyFormat(statusEssentials(he)))
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
+		he.Logger.Warnw("Unable to post status", "Handler", handlerName, "Error", err)
+		return false
 	}
-	be, err := h.loadOrder(he, order)
+	be.Status = lm
+	err = lm.Pin(he)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		he.Logger.Warnw("Unable to pin status", "Handler", handlerName, "Error", err)
 	}
-	msg := be.getCallText()
-	return berghandler.SendMessage(he, evt, handlerName, msg)
+	return true
 }
 
-func (h *BestellungHandler) getTotal(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order)
+// updateStatus edits the status message of be, note is shown above the table
+func updateStatus(he berghandler.HandlerEssentials, be Bestellung, note string) {
+	if !be.Status.IsSet() {
+		return
+	}
+	msg := be.prettyFormat(statusEssentials(he))
+	if note != "" {
+		msg = berghandler.JoinFormatted(berghandler.Paragraph(note), msg)
+	}
+	err := be.Status.Update(he, msg)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
+		he.Logger.Warnw("Unable to update status", "Handler", handlerName, "Error", err)
 	}
+}
+
+func (h *BestellungHandler) getCallText(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
-	msg := be.getTotal()
-	return berghandler.SendFormattedMessage(he, evt, handlerName, msg)
+	msg := be.getCallText(he)
+	return berghandler.SendMessage(he, evt, handlerName, msg)
 }
 
-func (h *BestellungHandler) printPayment(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order, payeds string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order, &payeds)
+func (h *BestellungHandler) getTotal(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
+	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
-	var payed float64
-	if payeds != "" {
-		p, err := strconv.ParseFloat(payeds, 64)
-		if err != nil {
-			return berghandler.SendMessage(he, evt, handlerName, "Zahlung konnte nicht konvertiert werden: "+err.Error())
-		}
-		payed = float64(p)
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
+	msg := be.getTotal(he)
+	return berghandler.SendFormattedMessage(he, evt, handlerName, msg)
+}
+
+func (h *BestellungHandler) printPayment(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
+	payed := args.Money("Gezahlt")
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
 	if payed != 0 {
 		be.Payed = payed
 	} else {
 		be.Payed = be.Total
 	}
-	err = he.Storage.EncodeFile(handlerName, order+".toml", storage.TOML, false, be)
+	err = he.Storage.EncodeFile(orderStorage, order+".toml", storage.TOML, true, be)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Speichern der bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.saveerror", err.Error()))
 	}
-	msg := be.getPayment()
+	msg := be.getPayment(he)
 	return berghandler.SendFormattedMessage(he, evt, handlerName, msg)
 }
 
-func (h *BestellungHandler) deletePosition(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order, posis string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order, &posis)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
-	posi, err := strconv.Atoi(posis)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Position konnte nicht konvertiert werden: "+err.Error())
-	}
+func (h *BestellungHandler) deletePosition(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
+	posi := args.Int("Position")
 
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
 
 	if (posi >= len(be.Positionen)) || (posi < 0) {
-		return berghandler.SendMessage(he, evt, handlerName, "Position nicht vorhanden")
-	}
-	if (!be.isCreator(evt.Sender.String())) && (!be.Positionen[posi].isBesteller(evt.Sender.String())) {
-		return berghandler.SendMessage(he, evt, handlerName, unauthorized)
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.position.notfound"))
 	}
 	be.removePosition(posi)
 	be.calcTotal()
-	err = he.Storage.EncodeFile(handlerName, order+".toml", storage.TOML, false, be)
+	err = he.Storage.EncodeFile(orderStorage, order+".toml", storage.TOML, true, be)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Speicerhn der bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.saveerror", err.Error()))
 	}
-	return berghandler.SendMessage(he, evt, handlerName, "Artikel entfernt")
+	updateStatus(he, be, "")
+	return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.article.removed"))
 }
 
-func (h *BestellungHandler) removeOrder(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) removeOrder(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
-	if !be.isCreator(evt.Sender.String()) {
-		return berghandler.SendMessage(he, evt, handlerName, unauthorized)
-	}
-	ex := he.Storage.DoesFileExist(handlerName, order+".toml", false)
+	ex := he.Storage.DoesFileExist(orderStorage, order+".toml", true)
 	if !ex {
-		return berghandler.SendMessage(he, evt, handlerName, "Bestellung nicht vorhanden")
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.order.notfound"))
 	}
-	err = he.Storage.DeleteFile(handlerName, order+".toml", false)
+	err = he.Storage.DeleteFile(orderStorage, order+".toml", true)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Error deleting file: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.deleteerror", err.Error()))
 	}
-	return berghandler.SendMessage(he, evt, handlerName, "Bestellung geschlossen")
+	countOrders(he)
+	updateStatus(he, be, berghandler.Tf(statusEssentials(he), "bestellung.status.closed", order))
+	if be.Status.IsSet() {
+		err = be.Status.Unpin(he)
+		if err != nil {
+			he.Logger.Warnw("Unable to unpin status", "Handler", handlerName, "Error", err)
+		}
+	}
+	return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.order.closed"))
 }
 
-func execHTTPRequest(URL string, method string, in io.Reader, v interface{}) error {
-	ctx, cncl := context.WithTimeout(context.Background(), time.Second*5)
+// execHTTPRequest gives up after 5 seconds or once the event being handled
+// timed out, whatever comes first
+func execHTTPRequest(parent context.Context, URL string, method string, in io.Reader, v interface{}) error {
+	ctx, cncl := context.WithTimeout(parent, time.Second*5)
 	defer cncl()
 
 	req, err := http.NewRequestWithContext(ctx, method, URL, in)
 	if err != nil {
-		return errors.New("Fehler beim Request erstellen: " + err.Error())
+		return berghandler.NewMessageError("bestellung.http.create", err.Error())
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return errors.New("Fehler beim Request ausführen: " + err.Error())
+		return berghandler.NewMessageError("bestellung.http.do", err.Error())
 	}
 
 	if v != nil {
@@ -366,18 +493,18 @@ func execHTTPRequest(URL string, method string, in io.Reader, v interface{}) err
 		if err != nil {
 			data, _ := io.ReadAll(resp.Body)
 			fmt.Println(string(data))
-			return errors.New("keine decodierung möglich: " + err.Error())
+			return berghandler.NewMessageError("bestellung.http.decode", err.Error())
 		}
 	}
 	return nil
 }
 
-func getStrichlistenID(address, name string) (int, error) {
+func getStrichlistenID(ctx context.Context, address, name string) (int, error) {
 
 	url := fmt.Sprintf(address+"/api/user/search?query=%v", name)
 
 	var userResponse siUserResponse
-	err := execHTTPRequest(url, http.MethodGet, nil, &userResponse)
+	err := execHTTPRequest(ctx, url, http.MethodGet, nil, &userResponse)
 	if err != nil {
 		return -1, err
 	}
@@ -386,7 +513,7 @@ func getStrichlistenID(address, name string) (int, error) {
 
 	switch userResponse.Count {
 	case 0:
-		return -1, errors.New("keinen Strichlisten Benutzer gefunden")
+		return -1, berghandler.NewMessageError("bestellung.strichliste.nouser")
 	case 1:
 		siUser = userResponse.SiUsers[0]
 	default:
@@ -399,46 +526,42 @@ func getStrichlistenID(address, name string) (int, error) {
 			}
 		}
 		if !found {
-			return -1, errors.New("keine exakte übereinstimmung gefunden. Bitte Name prüfen")
+			return -1, berghandler.NewMessageError("bestellung.strichliste.noexact")
 		}
 	}
 	if siUser.IsDisabled {
-		return -1, errors.New("benutzer disabled")
+		return -1, berghandler.NewMessageError("bestellung.strichliste.disabled")
 	}
 	return siUser.ID, nil
 }
 
-func (h *BestellungHandler) addStrichliste(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var username string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &username)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) addStrichliste(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	username := args.String("Benutzername")
 	var si strichlistenInfo
-	err = he.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
+	err := he.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Strichlisten Info: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.loaderror", err.Error()))
 	}
 	if si.Link == nil {
 		si.Link = make(map[string]int)
 	}
-	id, err := getStrichlistenID(si.Address, username)
+	id, err := getStrichlistenID(he.Context(), si.Address, username)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim finden des Strichlisten Users: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.finderror", berghandler.TError(he, err)))
 	}
 	si.Link[evt.Sender.String()] = id
 	err = he.Storage.EncodeFile(handlerName, "strichliste.toml", storage.TOML, true, si)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim speichern der Strichlisten Info: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.saveerror", err.Error()))
 	}
-	return berghandler.SendMessage(he, evt, handlerName, "Link hinzugefügt")
+	return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.strichliste.linked"))
 }
 
-func (h *BestellungHandler) removeStrichliste(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
+func (h *BestellungHandler) removeStrichliste(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
 	var si strichlistenInfo
 	err := he.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Strichlisten Info: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.loaderror", err.Error()))
 	}
 	if si.Link == nil {
 		si.Link = make(map[string]int)
@@ -446,9 +569,9 @@ func (h *BestellungHandler) removeStrichliste(he berghandler.HandlerEssentials,
 	delete(si.Link, evt.Sender.String())
 	err = he.Storage.EncodeFile(handlerName, "strichliste.toml", storage.TOML, true, si)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim speichern der Strichlisten Info: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.saveerror", err.Error()))
 	}
-	return berghandler.SendMessage(he, evt, handlerName, "Link entfernt")
+	return berghandler.SendSuccess(he, evt, handlerName, berghandler.T(he, "bestellung.strichliste.unlinked"))
 }
 
 func writePaymentResult(wg *sync.WaitGroup, ses *safeExecStatus, payee User, result string) {
@@ -458,29 +581,33 @@ func writePaymentResult(wg *sync.WaitGroup, ses *safeExecStatus, payee User, res
 	wg.Done()
 }
 
-func (h *BestellungHandler) doPayment(payer int, p paymentInfo, comment string, si strichlistenInfo, wg *sync.WaitGroup, ses *safeExecStatus) {
+func (h *BestellungHandler) doPayment(he berghandler.HandlerEssentials, payer int, p paymentInfo, comment string, si strichlistenInfo, wg *sync.WaitGroup, ses *safeExecStatus) {
 	siID, ex := si.Link[p.Payee.MatrixID]
 	if !ex {
-		writePaymentResult(wg, ses, p.Payee, "Keinen Strichlisten Benutzer gefunden")
+		strichlisteTransactions.WithLabelValues("nouser").Inc()
+		writePaymentResult(wg, ses, p.Payee, berghandler.T(he, "bestellung.payment.nouser"))
 		return
 	}
 
 	if payer == siID {
-		writePaymentResult(wg, ses, p.Payee, "Benutzer hat bei Lieferdienst bezahlt")
+		strichlisteTransactions.WithLabelValues("self").Inc()
+		writePaymentResult(wg, ses, p.Payee, berghandler.T(he, "bestellung.payment.self"))
 		return
 	}
 
 	url := fmt.Sprintf(si.Address+"/api/user/%v", siID)
 
 	var userResponse siUser
-	err := execHTTPRequest(url, http.MethodGet, nil, &userResponse)
+	err := execHTTPRequest(he.Context(), url, http.MethodGet, nil, &userResponse)
 	if err != nil {
-		writePaymentResult(wg, ses, p.Payee, "Fehler beim User Request:"+err.Error())
+		strichlisteTransactions.WithLabelValues("error").Inc()
+		writePaymentResult(wg, ses, p.Payee, berghandler.Tf(he, "bestellung.payment.userrequest", berghandler.TError(he, err)))
 		return
 	}
 
 	if userResponse.IsDisabled {
-		writePaymentResult(wg, ses, p.Payee, "Benutzer disabled")
+		strichlisteTransactions.WithLabelValues("disabled").Inc()
+		writePaymentResult(wg, ses, p.Payee, berghandler.T(he, "bestellung.payment.disabled"))
 		return
 	}
 
@@ -492,43 +619,38 @@ func (h *BestellungHandler) doPayment(payer int, p paymentInfo, comment string,
 	encoder.Encode(t)
 
 	url = fmt.Sprintf(si.Address+"/api/user/%v/transaction", siID)
-	err = execHTTPRequest(url, http.MethodPost, b, &to)
+	err = execHTTPRequest(he.Context(), url, http.MethodPost, b, &to)
 	if err != nil {
-		writePaymentResult(wg, ses, p.Payee, "Fehler beim Transaction Request:"+err.Error())
+		strichlisteTransactions.WithLabelValues("error").Inc()
+		writePaymentResult(wg, ses, p.Payee, berghandler.Tf(he, "bestellung.payment.transactionerror", berghandler.TError(he, err)))
 		return
 	}
 
-	writePaymentResult(wg, ses, p.Payee, fmt.Sprintf("Transaction mit der ID %v angelegt", to.ID))
+	strichlisteTransactions.WithLabelValues("ok").Inc()
+	writePaymentResult(wg, ses, p.Payee, berghandler.Tf(he, "bestellung.payment.done", to.ID))
 }
 
-func (h *BestellungHandler) processStrichliste(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var order string
-	var payer string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &order, &payer)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) processStrichliste(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	order := args.String("Bestellung")
+	payer := args.User("Bezahlendes-Wesen").String()
 	be, err := h.loadOrder(he, order)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Bestellung: "+err.Error())
-	}
-	if !be.isCreator(evt.Sender.String()) {
-		return berghandler.SendMessage(he, evt, handlerName, unauthorized)
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.order.loaderror", berghandler.TError(he, err)))
 	}
 	if be.Payed == 0 {
-		return berghandler.SendMessage(he, evt, handlerName, "Bestellung hat noch keinen gezahlten Geldwert")
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.payment.notpayed"))
 	}
 	var si strichlistenInfo
 	err = he.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
 	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, "Fehler beim Laden der Strichlisten Info: "+err.Error())
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "bestellung.strichliste.loaderror", err.Error()))
 	}
 	if payer == "" {
 		payer = be.Ersteller.MatrixID
 	}
 	siPayer, ex := si.Link[payer]
 	if !ex {
-		return berghandler.SendMessage(he, evt, handlerName, "Zahlender hat keine Strichliste verlinkt")
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "bestellung.payment.payernotlinked"))
 	}
 	var wg sync.WaitGroup
 	ses := safeExecStatus{es: make(map[User]string)}
@@ -537,41 +659,34 @@ func (h *BestellungHandler) processStrichliste(he berghandler.HandlerEssentials,
 	c := fmt.Sprintf("Bestellung bei %v am %v", be.LieferDienst, ti)
 	wg.Add(len(pi))
 	for _, p := range pi {
-		go h.doPayment(siPayer, p, c, si, &wg, &ses)
+		go h.doPayment(he, siPayer, p, c, si, &wg, &ses)
 	}
 	wg.Wait()
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Bestellung bei " + be.LieferDienst + " Strichlisten Abrechnung")
-	t.AppendHeader(table.Row{"Name", "Ergebnis"})
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.strichliste", be.LieferDienst))
+	t.AppendHeader(table.Row{berghandler.T(he, "bestellung.table.name"), berghandler.T(he, "bestellung.table.result")})
 	for p, r := range ses.es {
 		t.AppendRow(table.Row{p.MatrixID, r})
 	}
-	return berghandler.SendFormattedMessage(he, evt, handlerName, t.RenderHTML())
+	return berghandler.SendFormattedMessage(he, evt, handlerName, berghandler.RenderTable(t))
 }
 
-func (h *BestellungHandler) showMenu(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var ld string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &ld)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) showMenu(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	ld := args.String("Lieferdienst")
 	found, l := h.searchLieferdienst(ld)
 	if !found {
-		return berghandler.SendMessage(he, evt, handlerName, "Lieferdienst nicht gefunden, benutze !bestellung dienste für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, h.lieferdienstNotFound(he, ld))
 	}
-	return berghandler.SendFormattedMessage(he, evt, handlerName, l.prettyFormat())
+	return berghandler.SendFormattedMessage(he, evt, handlerName, l.prettyFormat(he))
 }
 
-func (h *BestellungHandler) showArticle(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	var ld, artikel string
-	err := berghandler.SplitAnswer(words, neededVariables, optionalVariables, &ld, &artikel)
-	if err != nil {
-		return berghandler.SendMessage(he, evt, handlerName, fmt.Sprintf(berghandler.WrongArguments, berghandler.CommandPrefix+command)+" "+err.Error())
-	}
+func (h *BestellungHandler) showArticle(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	ld := args.String("Lieferdienst")
+	artikel := args.String("Artikel")
 	found, l := h.searchLieferdienst(ld)
 	if !found {
-		return berghandler.SendMessage(he, evt, handlerName, "Lieferdienst nicht gefunden, benutze !bestellung dienste für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, h.lieferdienstNotFound(he, ld))
 	}
 
 	ex := false
@@ -584,23 +699,23 @@ func (h *BestellungHandler) showArticle(he berghandler.HandlerEssentials, evt *e
 		}
 	}
 	if !ex {
-		return berghandler.SendMessage(he, evt, handlerName, "Artikel nicht gefunden, benutze !bestellung menu $Lieferdienst für eine Liste")
+		return berghandler.SendMessage(he, evt, handlerName, articleNotFound(he, l, artikel))
 	}
 
-	return berghandler.SendFormattedMessage(he, evt, handlerName, desiredArtikel.prettyFormat())
+	return berghandler.SendFormattedMessage(he, evt, handlerName, desiredArtikel.prettyFormat(he))
 }
 
-func (h *BestellungHandler) prettyFormatRestaurants() string {
+func (h *BestellungHandler) prettyFormatRestaurants(he berghandler.HandlerEssentials) berghandler.Formatted {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Lieferdienste")
-	t.AppendHeader(table.Row{"#", "Name", "Telefonnummer"})
+	t.SetTitle(berghandler.T(he, catRestaurants))
+	t.AppendHeader(table.Row{"#", berghandler.T(he, "bestellung.table.name"), berghandler.T(he, "bestellung.table.phone")})
 	for i, l := range h.Lieferdienste {
 		t.AppendRow(table.Row{i, l.Name, l.Telefonnummer})
 	}
-	return t.RenderHTML()
+	return berghandler.RenderTable(t)
 }
 
-func (h *BestellungHandler) showRestaurants(he berghandler.HandlerEssentials, evt *event.Event, words []string, neededVariables, optionalVariables int) bool {
-	return berghandler.SendFormattedMessage(he, evt, handlerName, h.prettyFormatRestaurants())
+func (h *BestellungHandler) showRestaurants(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	return berghandler.SendFormattedMessage(he, evt, handlerName, h.prettyFormatRestaurants(he))
 }
@@ -1,20 +1,22 @@
 package bestellungHandler
 
 import (
-	"fmt"
 	"math"
 	"math/rand"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
 const handlerName = "BestellungHandler"
 const command = "bestellung"
 
-const unauthorized = "Nur der Bestellungs ersteller kann dieses Kommando ausführen"
+// orderStorage keeps the open orders apart from the restaurants and the
+// Strichliste, persistent so they survive restarts
+const orderStorage = handlerName + "/Bestellungen"
 
 func getRandomWord(slice []string) string {
 	return slice[rand.Intn(len(slice))]
@@ -32,15 +34,15 @@ type LieferDienst struct {
 	Artikel       []Artikel
 }
 
-func (ld *LieferDienst) prettyFormat() string {
+func (ld *LieferDienst) prettyFormat(he berghandler.HandlerEssentials) berghandler.Formatted {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Menü von " + ld.Name)
-	t.AppendHeader(table.Row{"#", "Artikel Nummer", "Artikel Name"})
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.menu", ld.Name))
+	t.AppendHeader(table.Row{"#", berghandler.T(he, "bestellung.table.articlenumber"), berghandler.T(he, "bestellung.table.articlename")})
 	for i, a := range ld.Artikel {
 		t.AppendRow(table.Row{i, a.Nummer, a.Name})
 	}
-	return t.RenderHTML()
+	return berghandler.RenderTable(t)
 }
 
 type Artikel struct {
@@ -51,20 +53,20 @@ type Artikel struct {
 	Extras    []Zusatz
 }
 
-func (a *Artikel) prettyFormat() string {
+func (a *Artikel) prettyFormat(he berghandler.HandlerEssentials) berghandler.Formatted {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Versionen und Extras von " + a.Name)
-	t.AppendHeader(table.Row{"#", "Version/Extra", "Preis"})
-	t.AppendRow(table.Row{"", "Versionen"})
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.article", a.Name))
+	t.AppendHeader(table.Row{"#", berghandler.T(he, "bestellung.table.versionextra"), berghandler.T(he, "bestellung.table.price")})
+	t.AppendRow(table.Row{"", berghandler.T(he, "bestellung.table.versions")})
 	for i, v := range a.Versionen {
 		t.AppendRow(table.Row{i, v.Name, v.Preis})
 	}
-	t.AppendRow(table.Row{"", "Extras"})
+	t.AppendRow(table.Row{"", berghandler.T(he, "bestellung.table.extras")})
 	for i, e := range a.Extras {
 		t.AppendRow(table.Row{i, e.Name, e.Preis})
 	}
-	return t.RenderHTML()
+	return berghandler.RenderTable(t)
 }
 
 type Zusatz struct {
@@ -81,6 +83,7 @@ type Bestellung struct {
 	Positionen   []Position
 	Total        float64
 	Payed        float64
+	Status       berghandler.LiveMessage //Pinned status message, edited on every change
 }
 
 func (b *Bestellung) removePosition(i int) {
@@ -89,18 +92,19 @@ func (b *Bestellung) removePosition(i int) {
 	}
 }
 
-func (b *Bestellung) prettyFormat() string {
+func (b *Bestellung) prettyFormat(he berghandler.HandlerEssentials) berghandler.Formatted {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Bestellung bei " + b.LieferDienst)
-	t.AppendHeader(table.Row{"#", "Nummer", "Name", "Version", "Anzahl", "Extras", "Kommentar", "Besteller"})
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.order", b.LieferDienst))
+	t.AppendHeader(table.Row{"#", berghandler.T(he, "bestellung.table.number"), berghandler.T(he, "bestellung.table.name"), berghandler.T(he, "bestellung.table.version"),
+		berghandler.T(he, "bestellung.table.amount"), berghandler.T(he, "bestellung.table.extras"), berghandler.T(he, "bestellung.table.comment"), berghandler.T(he, "bestellung.table.orderer")})
 	for i, p := range b.Positionen {
 		t.AppendRow(table.Row{i, p.ArtikelNummer, p.ArtikelName, p.Version, p.Anzahl, p.Extras, p.Kommentar, p.Besteller[0].DisplayName})
 	}
-	return t.RenderHTML()
+	return berghandler.RenderTable(t)
 }
 
-func (b *Bestellung) getCallText() string {
+func (b *Bestellung) getCallText(he berghandler.HandlerEssentials) string {
 	var newbestellung Bestellung
 	for _, p := range b.Positionen {
 		added := false
@@ -116,14 +120,14 @@ func (b *Bestellung) getCallText() string {
 			newbestellung.Positionen = append(newbestellung.Positionen, p)
 		}
 	}
-	result := "Lieferdienst: " + b.LieferDienst + "\n"
-	result = result + "Telefonnummer: " + b.Nummer + "\n\n"
-	result = result + "Hallo Nord mein Name ich würde gerne Bestellen und zwar: \n"
+	result := berghandler.Tf(he, "bestellung.call.restaurant", b.LieferDienst) + "\n"
+	result = result + berghandler.Tf(he, "bestellung.call.phone", b.Nummer) + "\n\n"
+	result = result + berghandler.T(he, "bestellung.call.greeting") + "\n"
 	for _, p := range newbestellung.Positionen {
 		if p.ArtikelNummer != "" {
-			result = result + fmt.Sprintf("%v mal die Nummer %v %v in %v mit %v %v\n", p.Anzahl, p.ArtikelNummer, p.ArtikelName, p.Version, p.Extras, p.Kommentar)
+			result = result + berghandler.Tf(he, "bestellung.call.numbered", p.Anzahl, p.ArtikelNummer, p.ArtikelName, p.Version, p.Extras, p.Kommentar) + "\n"
 		} else {
-			result = result + fmt.Sprintf("%v mal %v in %v mit %v %v\n", p.Anzahl, p.ArtikelName, p.Version, p.Extras, p.Kommentar)
+			result = result + berghandler.Tf(he, "bestellung.call.position", p.Anzahl, p.ArtikelName, p.Version, p.Extras, p.Kommentar) + "\n"
 		}
 	}
 	return result
@@ -146,17 +150,17 @@ func (b *Bestellung) calcTips() (float64, float64, float64, float64) {
 	return up, five, ten, twenty
 }
 
-func (b *Bestellung) getTotal() string {
+func (b *Bestellung) getTotal(he berghandler.HandlerEssentials) berghandler.Formatted {
 	up, five, ten, twenty := b.calcTips()
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Bestellung bei " + b.LieferDienst + " zu Zahlen")
-	t.AppendRow(table.Row{"Total", b.Total})
-	t.AppendRow(table.Row{"Aufgerundet", up})
-	t.AppendRow(table.Row{"5% Trinkgeld", five})
-	t.AppendRow(table.Row{"10% Trinkgeld", ten})
-	t.AppendRow(table.Row{"15% Trinkgeld", twenty})
-	return t.RenderHTML()
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.total", b.LieferDienst))
+	t.AppendRow(table.Row{berghandler.T(he, "bestellung.table.sum"), b.Total})
+	t.AppendRow(table.Row{berghandler.T(he, "bestellung.table.roundedup"), up})
+	t.AppendRow(table.Row{berghandler.Tf(he, "bestellung.table.tip", 5), five})
+	t.AppendRow(table.Row{berghandler.Tf(he, "bestellung.table.tip", 10), ten})
+	t.AppendRow(table.Row{berghandler.Tf(he, "bestellung.table.tip", 15), twenty})
+	return berghandler.RenderTable(t)
 }
 
 type paymentInfo struct {
@@ -184,17 +188,17 @@ func (b *Bestellung) calcPayment() ([]paymentInfo, float64) {
 	return result, off
 }
 
-func (b *Bestellung) getPayment() string {
+func (b *Bestellung) getPayment(he berghandler.HandlerEssentials) berghandler.Formatted {
 	pi, off := b.calcPayment()
 	t := table.NewWriter()
 	t.SetStyle(table.StyleColoredDark)
-	t.SetTitle("Bestellung bei " + b.LieferDienst + " Besteller Schulden")
-	t.AppendHeader(table.Row{"Rabatt", off})
-	t.AppendHeader(table.Row{"Name", "Schulden"})
+	t.SetTitle(berghandler.Tf(he, "bestellung.table.payment", b.LieferDienst))
+	t.AppendHeader(table.Row{berghandler.T(he, "bestellung.table.discount"), off})
+	t.AppendHeader(table.Row{berghandler.T(he, "bestellung.table.name"), berghandler.T(he, "bestellung.table.debt")})
 	for _, p := range pi {
 		t.AppendRow(table.Row{p.Payee.DisplayName, p.Amount})
 	}
-	return t.RenderHTML()
+	return berghandler.RenderTable(t)
 }
 
 func (b *Bestellung) isCreator(id string) bool {
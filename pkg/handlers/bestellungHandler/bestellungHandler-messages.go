@@ -0,0 +1,193 @@
+package bestellungHandler
+
+import (
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+)
+
+const catOrder = "bestellung.category.order"
+const catPayment = "bestellung.category.payment"
+const catStrichliste = "bestellung.category.strichliste"
+const catRestaurants = "bestellung.category.restaurants"
+
+func init() {
+	berghandler.RegisterMessages("de", berghandler.Messages{
+		"bestellung.description":              "Gemeinsame Bestellungen bei Lieferdiensten",
+		catOrder:                              "Bestellung",
+		catPayment:                            "Abrechnung",
+		catStrichliste:                        "Strichliste",
+		catRestaurants:                        "Lieferdienste",
+		"bestellung.help.new":                 "Erstellt eine Neue Bestellung, ohne Lieferdienst beim Standard des Raums.",
+		"bestellung.help.add":                 "Hinzufügen eines Items zur Bestellung",
+		"bestellung.help.show":                "Anzeigen einer Bestellung",
+		"bestellung.help.call-text":           "Ausgabe einen Textes zum Anrufen",
+		"bestellung.help.print-payment":       "Ausgabe der Informationen wer was bezahlen muss",
+		"bestellung.help.get-total":           "Ausgabe wie viel die Bestellung kostet plus Trinkgeld Vorschläge",
+		"bestellung.help.remove":              "Löscht Position aus der Bestellung",
+		"bestellung.help.close":               "Schließt Bestellung und Löscht diese",
+		"bestellung.help.add-strichliste":     "Verknüpft den schreibenden Matrix account mit einem Strichlisten Benutzer",
+		"bestellung.help.remove-strichliste":  "Löscht Matrix account zu Strichlisten account verknüpfung",
+		"bestellung.help.process-strichliste": "Versucht Bestellung via Strichliste abzurechenen",
+		"bestellung.help.menu":                "Zeigt Menü eines Lieferdienstes",
+		"bestellung.help.article":             "Zeigt Artikelinformationen",
+		"bestellung.help.restaurants":         "Zeigt alle Lieferdienste",
+
+		"bestellung.restaurant.missing":       "Kein Lieferdienst angegeben und kein Standard für diesen Raum gesetzt",
+		"bestellung.restaurant.notfound":      "Lieferdienst nicht gefunden, benutze %v restaurants für eine Liste.",
+		"bestellung.article.notfound":         "Artikel nicht gefunden, benutze %v menu %v für eine Liste.",
+		"bestellung.article.added":            "Artikel hinzugefügt",
+		"bestellung.article.removed":          "Artikel entfernt",
+		"bestellung.extras.read":              "Fehler beim lesen der Extras: %v",
+		"bestellung.extras.unknown":           "Konnte Zusatz %v nicht zuordnen",
+		"bestellung.extras.error":             "Fehler beim parsen der extras: %v. Benutze %v article %v %v für eine Liste",
+		"bestellung.order.created":            "Neue Bestellung mit dem Name: %v erstellt",
+		"bestellung.order.createerror":        "Fehler bei erstellung der Bestellung",
+		"bestellung.order.notfound":           "Bestellung nicht vorhanden",
+		"bestellung.order.decode":             "Fehler beim Laden der bestellung: %v",
+		"bestellung.order.loaderror":          "Fehler beim Laden der Bestellung: %v",
+		"bestellung.order.saveerror":          "Fehler beim Speichern der bestellung: %v",
+		"bestellung.order.deleteerror":        "Fehler beim Löschen der Bestellung: %v",
+		"bestellung.order.closed":             "Bestellung geschlossen",
+		"bestellung.status.updated":           "Status aktualisiert, siehe angepinnte Nachricht",
+		"bestellung.status.closed":            "Bestellung %v ist geschlossen",
+		"bestellung.position.notfound":        "Position nicht vorhanden",
+		"bestellung.http.create":              "Fehler beim Request erstellen: %v",
+		"bestellung.http.do":                  "Fehler beim Request ausführen: %v",
+		"bestellung.http.decode":              "keine decodierung möglich: %v",
+		"bestellung.strichliste.nouser":       "keinen Strichlisten Benutzer gefunden",
+		"bestellung.strichliste.noexact":      "keine exakte übereinstimmung gefunden. Bitte Name prüfen",
+		"bestellung.strichliste.disabled":     "benutzer disabled",
+		"bestellung.strichliste.loaderror":    "Fehler beim Laden der Strichlisten Info: %v",
+		"bestellung.strichliste.finderror":    "Fehler beim finden des Strichlisten Users: %v",
+		"bestellung.strichliste.saveerror":    "Fehler beim speichern der Strichlisten Info: %v",
+		"bestellung.strichliste.linked":       "Link hinzugefügt",
+		"bestellung.strichliste.unlinked":     "Link entfernt",
+		"bestellung.payment.nouser":           "Keinen Strichlisten Benutzer gefunden",
+		"bestellung.payment.self":             "Benutzer hat bei Lieferdienst bezahlt",
+		"bestellung.payment.userrequest":      "Fehler beim User Request: %v",
+		"bestellung.payment.disabled":         "Benutzer disabled",
+		"bestellung.payment.transactionerror": "Fehler beim Transaction Request: %v",
+		"bestellung.payment.done":             "Transaction mit der ID %v angelegt",
+		"bestellung.payment.notpayed":         "Bestellung hat noch keinen gezahlten Geldwert",
+		"bestellung.payment.payernotlinked":   "Zahlender hat keine Strichliste verlinkt",
+		"bestellung.call.restaurant":          "Lieferdienst: %v",
+		"bestellung.call.phone":               "Telefonnummer: %v",
+		"bestellung.call.greeting":            "Hallo Nord mein Name ich würde gerne Bestellen und zwar: ",
+		"bestellung.call.numbered":            "%v mal die Nummer %v %v in %v mit %v %v",
+		"bestellung.call.position":            "%v mal %v in %v mit %v %v",
+		"bestellung.table.menu":               "Menü von %v",
+		"bestellung.table.article":            "Versionen und Extras von %v",
+		"bestellung.table.order":              "Bestellung bei %v",
+		"bestellung.table.total":              "Bestellung bei %v zu Zahlen",
+		"bestellung.table.payment":            "Bestellung bei %v Besteller Schulden",
+		"bestellung.table.strichliste":        "Bestellung bei %v Strichlisten Abrechnung",
+		"bestellung.table.articlenumber":      "Artikel Nummer",
+		"bestellung.table.articlename":        "Artikel Name",
+		"bestellung.table.versionextra":       "Version/Extra",
+		"bestellung.table.price":              "Preis",
+		"bestellung.table.versions":           "Versionen",
+		"bestellung.table.extras":             "Extras",
+		"bestellung.table.number":             "Nummer",
+		"bestellung.table.name":               "Name",
+		"bestellung.table.version":            "Version",
+		"bestellung.table.amount":             "Anzahl",
+		"bestellung.table.comment":            "Kommentar",
+		"bestellung.table.orderer":            "Besteller",
+		"bestellung.table.sum":                "Total",
+		"bestellung.table.roundedup":          "Aufgerundet",
+		"bestellung.table.tip":                "%v%% Trinkgeld",
+		"bestellung.table.discount":           "Rabatt",
+		"bestellung.table.debt":               "Schulden",
+		"bestellung.table.result":             "Ergebnis",
+		"bestellung.table.phone":              "Telefonnummer",
+	})
+	berghandler.RegisterMessages("en", berghandler.Messages{
+		"bestellung.description":              "Group orders at restaurants",
+		catOrder:                              "Order",
+		catPayment:                            "Payment",
+		catStrichliste:                        "Strichliste",
+		catRestaurants:                        "Restaurants",
+		"bestellung.help.new":                 "Creates a new order, without restaurant at the default of the room.",
+		"bestellung.help.add":                 "Adds an item to the order",
+		"bestellung.help.show":                "Shows an order",
+		"bestellung.help.call-text":           "Prints a text for calling the restaurant",
+		"bestellung.help.print-payment":       "Prints who has to pay what",
+		"bestellung.help.get-total":           "Prints the total of the order plus tip suggestions",
+		"bestellung.help.remove":              "Removes a position from the order",
+		"bestellung.help.close":               "Closes and deletes the order",
+		"bestellung.help.add-strichliste":     "Links your Matrix account with a Strichliste user",
+		"bestellung.help.remove-strichliste":  "Removes the link between your Matrix account and Strichliste",
+		"bestellung.help.process-strichliste": "Tries to settle the order via Strichliste",
+		"bestellung.help.menu":                "Shows the menu of a restaurant",
+		"bestellung.help.article":             "Shows information about an article",
+		"bestellung.help.restaurants":         "Shows all restaurants",
+
+		"bestellung.restaurant.missing":       "No restaurant given and no default set for this room",
+		"bestellung.restaurant.notfound":      "Restaurant not found, use %v restaurants for a list.",
+		"bestellung.article.notfound":         "Article not found, use %v menu %v for a list.",
+		"bestellung.article.added":            "Article added",
+		"bestellung.article.removed":          "Article removed",
+		"bestellung.extras.read":              "Error reading the extras: %v",
+		"bestellung.extras.unknown":           "Could not match the extra %v",
+		"bestellung.extras.error":             "Error parsing the extras: %v. Use %v article %v %v for a list",
+		"bestellung.order.created":            "New order created with the name: %v",
+		"bestellung.order.createerror":        "Error creating the order",
+		"bestellung.order.notfound":           "Order does not exist",
+		"bestellung.order.decode":             "Error decoding the order: %v",
+		"bestellung.order.loaderror":          "Error loading the order: %v",
+		"bestellung.order.saveerror":          "Error saving the order: %v",
+		"bestellung.order.deleteerror":        "Error deleting the order: %v",
+		"bestellung.order.closed":             "Order closed",
+		"bestellung.status.updated":           "Status updated, see the pinned message",
+		"bestellung.status.closed":            "Order %v is closed",
+		"bestellung.position.notfound":        "Position does not exist",
+		"bestellung.http.create":              "Error creating the request: %v",
+		"bestellung.http.do":                  "Error executing the request: %v",
+		"bestellung.http.decode":              "Error decoding the response: %v",
+		"bestellung.strichliste.nouser":       "no Strichliste user found",
+		"bestellung.strichliste.noexact":      "no exact match found, please check the name",
+		"bestellung.strichliste.disabled":     "user disabled",
+		"bestellung.strichliste.loaderror":    "Error loading the Strichliste info: %v",
+		"bestellung.strichliste.finderror":    "Error finding the Strichliste user: %v",
+		"bestellung.strichliste.saveerror":    "Error saving the Strichliste info: %v",
+		"bestellung.strichliste.linked":       "Link added",
+		"bestellung.strichliste.unlinked":     "Link removed",
+		"bestellung.payment.nouser":           "No Strichliste user found",
+		"bestellung.payment.self":             "User paid the restaurant",
+		"bestellung.payment.userrequest":      "Error in the user request: %v",
+		"bestellung.payment.disabled":         "User disabled",
+		"bestellung.payment.transactionerror": "Error in the transaction request: %v",
+		"bestellung.payment.done":             "Transaction created with the ID %v",
+		"bestellung.payment.notpayed":         "The order has no paid amount yet",
+		"bestellung.payment.payernotlinked":   "The payer has no linked Strichliste",
+		"bestellung.call.restaurant":          "Restaurant: %v",
+		"bestellung.call.phone":               "Phone number: %v",
+		"bestellung.call.greeting":            "Hello, my name is ... and I would like to order: ",
+		"bestellung.call.numbered":            "%v times number %v %v in %v with %v %v",
+		"bestellung.call.position":            "%v times %v in %v with %v %v",
+		"bestellung.table.menu":               "Menu of %v",
+		"bestellung.table.article":            "Versions and extras of %v",
+		"bestellung.table.order":              "Order at %v",
+		"bestellung.table.total":              "Order at %v to pay",
+		"bestellung.table.payment":            "Order at %v debts",
+		"bestellung.table.strichliste":        "Order at %v Strichliste settlement",
+		"bestellung.table.articlenumber":      "Article number",
+		"bestellung.table.articlename":        "Article name",
+		"bestellung.table.versionextra":       "Version/Extra",
+		"bestellung.table.price":              "Price",
+		"bestellung.table.versions":           "Versions",
+		"bestellung.table.extras":             "Extras",
+		"bestellung.table.number":             "Number",
+		"bestellung.table.name":               "Name",
+		"bestellung.table.version":            "Version",
+		"bestellung.table.amount":             "Amount",
+		"bestellung.table.comment":            "Comment",
+		"bestellung.table.orderer":            "Ordered by",
+		"bestellung.table.sum":                "Total",
+		"bestellung.table.roundedup":          "Rounded up",
+		"bestellung.table.tip":                "%v%% tip",
+		"bestellung.table.discount":           "Discount",
+		"bestellung.table.debt":               "Debt",
+		"bestellung.table.result":             "Result",
+		"bestellung.table.phone":              "Phone number",
+	})
+}
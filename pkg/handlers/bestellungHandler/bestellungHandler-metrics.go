@@ -0,0 +1,38 @@
+package bestellungHandler
+
+import (
+	"strings"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	openOrders = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "bergknecht", Subsystem: "bestellung", Name: "open_orders", Help: "Orders that are not closed",
+	})
+	strichlisteTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bergknecht", Subsystem: "bestellung", Name: "strichliste_transactions_total", Help: "Strichliste payments by result",
+	}, []string{"result"})
+)
+
+func init() {
+	metrics.MustRegister(openOrders, strichlisteTransactions)
+}
+
+// countOrders sets the open orders from the stored ones
+func countOrders(he berghandler.HandlerEssentials) {
+	files, err := he.Storage.ListFiles(orderStorage, true)
+	if err != nil {
+		he.Logger.Warnw("Unable to count orders", "Handler", handlerName, "Error", err)
+		return
+	}
+	n := 0
+	for _, f := range files {
+		if strings.HasSuffix(f, ".toml") {
+			n++
+		}
+	}
+	openOrders.Set(float64(n))
+}
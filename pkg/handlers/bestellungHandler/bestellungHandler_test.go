@@ -0,0 +1,562 @@
+package bestellungHandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler/bergtest"
+	"github.com/Nerdbergev/Bergknecht/pkg/storage"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const otherUser = id.UserID("@alice:example.org")
+const adminUser = id.UserID("@admin:example.org")
+
+var testMenu = BestellungHandler{Lieferdienste: []LieferDienst{{
+	Name:          "Pizzeria",
+	Telefonnummer: "0911",
+	Artikel: []Artikel{
+		{
+			Nummer:    "1",
+			Name:      "Margherita",
+			Versionen: []Zusatz{{Name: "klein", Preis: 7.5}, {Name: "gross", Preis: 10}},
+			Extras:    []Zusatz{{Name: "kaese", Preis: 1}},
+		},
+		{Nummer: "2", Name: "Salat", Versionen: []Zusatz{{Name: "normal", Preis: 5}}},
+	},
+}}}
+
+func newHarness(t *testing.T) *bergtest.Harness {
+	t.Helper()
+	h := bergtest.NewHarness(&BestellungHandler{}, t.TempDir())
+	h.HE.Permissions.Admins = []string{adminUser.String()}
+	err := h.HE.Storage.EncodeFile(handlerName, "lieferdienste.toml", storage.TOML, true, testMenu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = h.Prime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// send fails the test unless the handler took the command
+func send(t *testing.T, h *bergtest.Harness, sender id.UserID, body string) []string {
+	t.Helper()
+	handled, replies := h.SendAs(sender, berghandler.DefaultCommandPrefix+command+" "+body)
+	if !handled {
+		t.Fatalf("%v was not handled, replies: %q", body, replies)
+	}
+	return replies
+}
+
+// wantReply fails the test unless the last reply starts with want
+func wantReply(t *testing.T, replies []string, want string) {
+	t.Helper()
+	if len(replies) == 0 || !strings.HasPrefix(replies[len(replies)-1], want) {
+		t.Fatalf("replies = %q, want the last one to start with %q", replies, want)
+	}
+}
+
+// orders returns the names of the stored orders
+func orders(t *testing.T, h *bergtest.Harness) []string {
+	t.Helper()
+	files, err := h.HE.Storage.ListFiles(orderStorage, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, f := range files {
+		result = append(result, strings.TrimSuffix(f, ".toml"))
+	}
+	return result
+}
+
+func stored(t *testing.T, h *bergtest.Harness, order string) Bestellung {
+	t.Helper()
+	var be Bestellung
+	err := h.HE.Storage.DecodeFile(orderStorage, order+".toml", storage.TOML, true, &be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return be
+}
+
+func pinned(h *bergtest.Harness) []id.EventID {
+	var content event.PinnedEventsEventContent
+	h.Client.StateEvent(h.RoomID, event.StatePinnedEvents, "", &content)
+	return content.Pinned
+}
+
+// newOrder opens an order of the default sender and returns its name
+func newOrder(t *testing.T, h *bergtest.Harness) string {
+	t.Helper()
+	before := orders(t, h)
+	send(t, h, h.Sender, "new pizzeria")
+	names := orders(t, h)
+	if len(names) != len(before)+1 {
+		t.Fatalf("%v orders stored, want %v", len(names), len(before)+1)
+	}
+	for _, n := range names {
+		known := false
+		for _, b := range before {
+			known = known || b == n
+		}
+		if !known {
+			return n
+		}
+	}
+	return ""
+}
+
+func TestNewOrder(t *testing.T) {
+	h := newHarness(t)
+	order := newOrder(t, h)
+	wantReply(t, []string{h.LastReply()}, berghandler.Tf(h.HE, "bestellung.order.created", order))
+
+	be := stored(t, h, order)
+	if be.LieferDienst != "pizzeria" || be.Nummer != "0911" || be.Ersteller.MatrixID != h.Sender.String() {
+		t.Errorf("stored order = %+v", be)
+	}
+	if !be.Status.IsSet() || be.Status.RoomID != h.RoomID {
+		t.Fatalf("status = %+v, want it posted in the room", be.Status)
+	}
+	if p := pinned(h); len(p) != 1 || p[0] != be.Status.EventID {
+		t.Errorf("pinned = %v, want the status %v", p, be.Status.EventID)
+	}
+
+	_, replies := h.Send("!bestellung new")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.restaurant.missing"))
+	h.HE.Room.DefaultRestaurant = "Pizzeria"
+	_, replies = h.Send("!bestellung new")
+	wantReply(t, replies, strings.SplitN(berghandler.T(h.HE, "bestellung.order.created"), "%v", 2)[0])
+	if n := len(orders(t, h)); n != 2 {
+		t.Errorf("%v orders stored, want 2", n)
+	}
+	_, replies = h.Send("!bestellung new burgerladen")
+	wantReply(t, replies, berghandler.Tf(h.HE, "bestellung.restaurant.notfound", "!bestellung"))
+}
+
+func TestAddAndRemove(t *testing.T) {
+	h := newHarness(t)
+	order := newOrder(t, h)
+
+	replies := send(t, h, h.Sender, "add "+order+" margherita gross kaese")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.article.added"))
+	if len(replies) != 2 || !strings.Contains(replies[0], "Margherita") {
+		t.Errorf("replies = %q, want the edited status before the confirmation", replies)
+	}
+	replies = send(t, h, otherUser, `add `+order+` 2 anzahl=2 kommentar="Ohne Dressing"`)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.article.added"))
+
+	be := stored(t, h, order)
+	if len(be.Positionen) != 2 {
+		t.Fatalf("positions = %+v, want 2", be.Positionen)
+	}
+	p := be.Positionen[0]
+	if p.ArtikelName != "Margherita" || p.Version != "gross" || p.Extras != "kaese" || p.Einzelpreis != 11 || p.Anzahl != 1 || p.Besteller[0].MatrixID != h.Sender.String() {
+		t.Errorf("first position = %+v", p)
+	}
+	p = be.Positionen[1]
+	if p.ArtikelName != "Salat" || p.Anzahl != 2 || p.Kommentar != "Ohne Dressing" || p.Besteller[0].MatrixID != otherUser.String() {
+		t.Errorf("second position = %+v", p)
+	}
+	if be.Total != 21 {
+		t.Errorf("total = %v, want 21", be.Total)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unknown article", "add " + order + " calzone", berghandler.Tf(h.HE, "bestellung.article.notfound", "!bestellung", "pizzeria")},
+		{"unknown extra", "add " + order + " margherita gross salami", "Fehler beim parsen der extras"},
+		{"unknown order", "add eins-rote-affen margherita", berghandler.Tf(h.HE, "bestellung.order.loaderror", berghandler.T(h.HE, "bestellung.order.notfound"))},
+		{"no article", "add " + order, strings.SplitN(berghandler.T(h.HE, "args.invalid"), "%v", 2)[0]},
+		{"no order name", "add pizza margherita", strings.SplitN(berghandler.T(h.HE, "args.invalid"), "%v", 2)[0]},
+		{"position no int", "remove " + order + " erste", strings.SplitN(berghandler.T(h.HE, "args.invalid"), "%v", 2)[0]},
+		{"unknown position", "remove " + order + " 5", berghandler.T(h.HE, "bestellung.position.notfound")},
+	}
+	for _, tt := range tests {
+		wantReply(t, send(t, h, h.Sender, tt.body), tt.want)
+	}
+	if n := len(stored(t, h, order).Positionen); n != 2 {
+		t.Fatalf("failed commands changed the order to %v positions", n)
+	}
+
+	// Only the creator of the order, the orderer and admins may remove
+	replies = send(t, h, "@bob:example.org", "remove "+order+" 1")
+	wantReply(t, replies, berghandler.Tf(h.HE, "command.notowner", berghandler.T(h.HE, "role.admin")))
+	replies = send(t, h, otherUser, "remove "+order+" 0")
+	wantReply(t, replies, berghandler.Tf(h.HE, "command.notowner", berghandler.T(h.HE, "role.admin")))
+	replies = send(t, h, otherUser, "remove "+order+" 1")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.article.removed"))
+	replies = send(t, h, adminUser, "remove "+order+" 0")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.article.removed"))
+	be = stored(t, h, order)
+	if len(be.Positionen) != 0 || be.Total != 0 {
+		t.Errorf("order after removing = %+v, want it empty", be)
+	}
+}
+
+func TestShowAndPayment(t *testing.T) {
+	h := newHarness(t)
+	order := newOrder(t, h)
+	send(t, h, h.Sender, "add "+order+" margherita klein")
+	send(t, h, otherUser, "add "+order+" 1 gross anzahl=2")
+
+	replies := send(t, h, h.Sender, "show "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.status.updated"))
+
+	// Another room gets the status posted there only if there is none yet
+	h.RoomID = "!other:example.org"
+	replies = send(t, h, h.Sender, "show "+order)
+	if len(replies) != 1 || !strings.Contains(replies[0], "Margherita") {
+		t.Errorf("show in another room = %q, want the table", replies)
+	}
+	h.RoomID = bergtest.DefaultRoom
+
+	replies = send(t, h, h.Sender, "call-text "+order)
+	for _, want := range []string{"0911", "1 mal die Nummer 1 Margherita in klein", "2 mal die Nummer 1 Margherita in gross"} {
+		if !strings.Contains(replies[0], want) {
+			t.Errorf("call text %q does not contain %q", replies[0], want)
+		}
+	}
+
+	replies = send(t, h, h.Sender, "get-total "+order)
+	if !strings.Contains(replies[0], "27.5") || !strings.Contains(replies[0], "28") {
+		t.Errorf("total %q does not contain the sum and the rounded sum", replies[0])
+	}
+
+	send(t, h, h.Sender, "print-payment "+order)
+	if be := stored(t, h, order); be.Payed != be.Total {
+		t.Errorf("payed = %v, want the total %v", be.Payed, be.Total)
+	}
+	replies = send(t, h, h.Sender, "print-payment "+order+" 22")
+	if be := stored(t, h, order); be.Payed != 22 {
+		t.Errorf("payed = %v, want 22", be.Payed)
+	}
+	if !strings.Contains(replies[0], "tester") || !strings.Contains(replies[0], "alice") || !strings.Contains(replies[0], "16") {
+		t.Errorf("payment %q does not list everyone with their share", replies[0])
+	}
+
+	for _, cmd := range []string{"show", "call-text", "get-total", "print-payment"} {
+		replies = send(t, h, h.Sender, cmd+" eins-rote-affen")
+		wantReply(t, replies, berghandler.Tf(h.HE, "bestellung.order.loaderror", berghandler.T(h.HE, "bestellung.order.notfound")))
+	}
+}
+
+func TestCloseOrder(t *testing.T) {
+	h := newHarness(t)
+	order := newOrder(t, h)
+	status := stored(t, h, order).Status
+
+	replies := send(t, h, otherUser, "close "+order)
+	wantReply(t, replies, berghandler.Tf(h.HE, "command.notowner", berghandler.T(h.HE, "role.admin")))
+	if len(orders(t, h)) != 1 {
+		t.Fatal("order was closed by someone else")
+	}
+
+	replies = send(t, h, h.Sender, "close "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.order.closed"))
+	if !strings.Contains(replies[0], berghandler.Tf(h.HE, "bestellung.status.closed", order)) {
+		t.Errorf("status edit %q does not say that the order is closed", replies[0])
+	}
+	if len(orders(t, h)) != 0 {
+		t.Error("closed order is still stored")
+	}
+	for _, p := range pinned(h) {
+		if p == status.EventID {
+			t.Error("status of the closed order is still pinned")
+		}
+	}
+	replies = send(t, h, h.Sender, "close "+order)
+	wantReply(t, replies, berghandler.Tf(h.HE, "bestellung.order.loaderror", berghandler.T(h.HE, "bestellung.order.notfound")))
+
+	order = newOrder(t, h)
+	replies = send(t, h, adminUser, "close "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.order.closed"))
+}
+
+// fakeStrichliste answers like the API of a Strichliste with the users
+// tester (1), alice (2) and the disabled bob (3)
+type fakeStrichliste struct {
+	transactions map[string]siTransaction
+}
+
+func (fs *fakeStrichliste) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	users := []siUser{{ID: 1, Name: "tester"}, {ID: 2, Name: "alice"}, {ID: 3, Name: "bob", IsDisabled: true}}
+	switch {
+	case r.URL.Path == "/api/user/search":
+		var found []siUser
+		for _, u := range users {
+			if strings.Contains(u.Name, strings.ToLower(r.URL.Query().Get("query"))) {
+				found = append(found, u)
+			}
+		}
+		json.NewEncoder(w).Encode(siUserResponse{Count: len(found), SiUsers: found})
+	case strings.HasSuffix(r.URL.Path, "/transaction") && r.Method == http.MethodPost:
+		var tr siTransaction
+		json.NewDecoder(r.Body).Decode(&tr)
+		fs.transactions[r.URL.Path] = tr
+		json.NewEncoder(w).Encode(siTransactionOJ{ID: 99})
+	case strings.HasPrefix(r.URL.Path, "/api/user/"):
+		for _, u := range users {
+			if r.URL.Path == "/api/user/"+strconv.Itoa(u.ID) {
+				json.NewEncoder(w).Encode(u)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestStrichliste(t *testing.T) {
+	fs := &fakeStrichliste{transactions: make(map[string]siTransaction)}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+
+	h := newHarness(t)
+	err := h.HE.Storage.EncodeFile(handlerName, "strichliste.toml", storage.TOML, true, strichlistenInfo{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := newOrder(t, h)
+	send(t, h, h.Sender, "add "+order+" margherita klein")
+	send(t, h, otherUser, "add "+order+" salat")
+
+	replies := send(t, h, h.Sender, "process-strichliste "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.payment.notpayed"))
+	send(t, h, h.Sender, "print-payment "+order)
+	replies = send(t, h, h.Sender, "process-strichliste "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.payment.payernotlinked"))
+
+	replies = send(t, h, h.Sender, "add-strichliste Tester")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.strichliste.linked"))
+	replies = send(t, h, otherUser, "add-strichliste alice")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.strichliste.linked"))
+	replies = send(t, h, "@bob:example.org", "add-strichliste bob")
+	wantReply(t, replies, berghandler.Tf(h.HE, "bestellung.strichliste.finderror", berghandler.T(h.HE, "bestellung.strichliste.disabled")))
+	replies = send(t, h, "@bob:example.org", "add-strichliste carol")
+	wantReply(t, replies, berghandler.Tf(h.HE, "bestellung.strichliste.finderror", berghandler.T(h.HE, "bestellung.strichliste.nouser")))
+
+	var si strichlistenInfo
+	err = h.HE.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(si.Link) != 2 || si.Link[h.Sender.String()] != 1 || si.Link[otherUser.String()] != 2 {
+		t.Fatalf("links = %v", si.Link)
+	}
+
+	replies = send(t, h, otherUser, "process-strichliste "+order)
+	wantReply(t, replies, berghandler.Tf(h.HE, "command.notowner", berghandler.T(h.HE, "role.admin")))
+	if len(fs.transactions) != 0 {
+		t.Fatal("someone else booked the order")
+	}
+	replies = send(t, h, h.Sender, "process-strichliste "+order)
+	for _, want := range []string{berghandler.T(h.HE, "bestellung.payment.self"), berghandler.Tf(h.HE, "bestellung.payment.done", 99)} {
+		if !strings.Contains(replies[0], want) {
+			t.Errorf("result %q does not contain %q", replies[0], want)
+		}
+	}
+	tr, ex := fs.transactions["/api/user/2/transaction"]
+	if len(fs.transactions) != 1 || !ex || tr.Amount != 500 || tr.RecipientID != 1 {
+		t.Errorf("transactions = %+v, want 5€ from alice to tester", fs.transactions)
+	}
+
+	replies = send(t, h, otherUser, "remove-strichliste")
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.strichliste.unlinked"))
+	si = strichlistenInfo{}
+	h.HE.Storage.DecodeFile(handlerName, "strichliste.toml", storage.TOML, true, &si)
+	if _, ex := si.Link[otherUser.String()]; ex || len(si.Link) != 1 {
+		t.Errorf("links after unlinking = %v", si.Link)
+	}
+}
+
+func TestRestaurants(t *testing.T) {
+	h := newHarness(t)
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"restaurants", "Pizzeria"},
+		{"menu pizzeria", "Salat"},
+		{"article pizzeria margherita", "kaese"},
+		{"article pizzeria 2", "normal"},
+	}
+	for _, tt := range tests {
+		replies := send(t, h, h.Sender, tt.body)
+		if len(replies) != 1 || !strings.Contains(replies[0], tt.want) {
+			t.Errorf("%v = %q, want it to contain %q", tt.body, replies, tt.want)
+		}
+	}
+	wantReply(t, send(t, h, h.Sender, "menu burgerladen"), berghandler.Tf(h.HE, "bestellung.restaurant.notfound", "!bestellung"))
+	wantReply(t, send(t, h, h.Sender, "article pizzeria calzone"), berghandler.Tf(h.HE, "bestellung.article.notfound", "!bestellung", "pizzeria"))
+	wantReply(t, send(t, h, h.Sender, "menu"), strings.SplitN(berghandler.T(h.HE, "args.invalid"), "%v", 2)[0])
+}
+
+func TestUnknownCommand(t *testing.T) {
+	h := newHarness(t)
+	replies := send(t, h, h.Sender, "bestelle pizza")
+	wantReply(t, replies, berghandler.Tf(h.HE, "command.unknown", "!bestellung"))
+	replies = send(t, h, h.Sender, "help")
+	if len(replies) != 1 || !strings.Contains(replies[0], "process-strichliste") {
+		t.Errorf("help = %q, want all commands listed", replies)
+	}
+	handled, _ := h.Send("!echo hallo")
+	if handled {
+		t.Error("handler took a command of another handler")
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	h := newHarness(t)
+	order := newOrder(t, h)
+	h.Client.Err = errors.New("homeserver down")
+
+	handled, _ := h.Send("!bestellung add " + order + " margherita klein")
+	if handled {
+		t.Error("add reported success although no reply was sent")
+	}
+	if n := len(stored(t, h, order).Positionen); n != 1 {
+		t.Errorf("%v positions stored, want the article saved without the reply", n)
+	}
+
+	handled, _ = h.Send("!bestellung new pizzeria")
+	if handled {
+		t.Error("new reported success although no reply was sent")
+	}
+	names := orders(t, h)
+	if len(names) != 2 {
+		t.Fatalf("%v orders stored, want the new one saved without the reply", len(names))
+	}
+	for _, n := range names {
+		if n != order && stored(t, h, n).Status.IsSet() {
+			t.Error("status of a message that was never sent is stored")
+		}
+	}
+
+	h.Client.Err = nil
+	replies := send(t, h, h.Sender, "show "+order)
+	wantReply(t, replies, berghandler.T(h.HE, "bestellung.status.updated"))
+}
+
+func TestMigrateCachedOrders(t *testing.T) {
+	dir := t.TempDir()
+	h := bergtest.NewHarness(&BestellungHandler{}, dir)
+	err := h.HE.Storage.EncodeFile(handlerName, "lieferdienste.toml", storage.TOML, true, testMenu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Bestellung{LieferDienst: "pizzeria", Ersteller: User{"tester", bergtest.DefaultSender.String()}}
+	err = h.HE.Storage.EncodeFile(handlerName, "drei-rote-affen.toml", storage.TOML, false, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = h.Prime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := orders(t, h); len(names) != 1 || names[0] != "drei-rote-affen" {
+		t.Fatalf("orders = %v, want the cached one migrated", names)
+	}
+	if h.HE.Storage.DoesFileExist(handlerName, "drei-rote-affen.toml", false) {
+		t.Error("cached order was not removed")
+	}
+	if !h.HE.Storage.DoesFileExist(handlerName, "lieferdienste.toml", true) {
+		t.Error("restaurants were migrated as an order")
+	}
+
+	err = h.HE.Storage.DeleteCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders(t, h)) != 1 {
+		t.Error("deleting the cache dropped the open order")
+	}
+}
+
+func TestTimedOutEvent(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	h := newHarness(t)
+	err := h.HE.Storage.EncodeFile(handlerName, "strichliste.toml", storage.TOML, true, strichlistenInfo{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	h.HE.Ctx = ctx
+	start := time.Now()
+	handled, replies := h.Send("!bestellung add-strichliste tester")
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("request to the Strichliste ran %v past the deadline of the event", d)
+	}
+	if handled || len(replies) != 0 {
+		t.Errorf("handled = %v, replies = %q, want nothing sent after the deadline", handled, replies)
+	}
+}
+
+func TestGuestRefused(t *testing.T) {
+	h := newHarness(t)
+	h.HE.Permissions.DefaultRole = "guest"
+	h.HE.Permissions.Members = []string{h.Sender.String(), otherUser.String()}
+	order := newOrder(t, h)
+	send(t, h, h.Sender, "add "+order+" margherita klein")
+	guest := id.UserID("@guest:example.org")
+	member := berghandler.Tf(h.HE, "command.unauthorized", berghandler.T(h.HE, "role.member"))
+	notOwner := berghandler.Tf(h.HE, "command.notowner", berghandler.T(h.HE, "role.admin"))
+
+	tests := []struct {
+		sender id.UserID
+		body   string
+		want   string
+	}{
+		{guest, "new pizzeria", member},
+		{guest, "add " + order + " salat", member},
+		{guest, "add-strichliste tester", member},
+		{guest, "remove-strichliste", member},
+		{guest, "print-payment " + order + " 1", notOwner},
+		{guest, "close " + order, notOwner},
+		{otherUser, "print-payment " + order + " 1", notOwner},
+	}
+	for _, tt := range tests {
+		wantReply(t, send(t, h, tt.sender, tt.body), tt.want)
+	}
+	if n := len(orders(t, h)); n != 1 {
+		t.Errorf("%v orders stored, want only the one of the member", n)
+	}
+	be := stored(t, h, order)
+	if len(be.Positionen) != 1 || be.Payed != 0 {
+		t.Errorf("order = %+v, want it unchanged by the refused commands", be)
+	}
+
+	// Reading stays open to guests
+	replies := send(t, h, guest, "get-total "+order)
+	if len(replies) != 1 || !strings.Contains(replies[0], "7.5") {
+		t.Errorf("get-total of a guest = %q", replies)
+	}
+	send(t, h, adminUser, "print-payment "+order+" 8")
+	if be := stored(t, h, order); be.Payed != 8 {
+		t.Errorf("payed = %v, want the 8 set by the admin", be.Payed)
+	}
+}
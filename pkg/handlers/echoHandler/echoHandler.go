@@ -11,17 +11,27 @@ var handlerName = "EchoHandler"
 type EchoHandler struct {
 }
 
+func init() {
+	berghandler.RegisterHandler(EchoHandler{})
+}
+
 func (h EchoHandler) GetName() string {
 	return handlerName
 }
 
+func (h EchoHandler) GetCommand() string {
+	return ""
+}
+
+func (h EchoHandler) Prime(he berghandler.HandlerEssentials) error {
+	return nil
+}
+
 func (h EchoHandler) Handle(he berghandler.HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
 	if evt.Type == event.EventMessage {
 		m := evt.Content.AsMessage()
 		he.Logger.Infow("Message recieved", "Handler", handlerName, "message", m.Body)
-		_, err := he.Client.SendText(evt.RoomID, m.Body)
-		if err != nil {
-			he.Logger.Errorw("Error sending Message", "Handler", handlerName, "Error", err)
+		if !berghandler.SendMessage(he, evt, handlerName, m.Body) {
 			return false
 		}
 		f, err := he.Storage.GetFileWriting(h.GetName(), "log.txt", true)
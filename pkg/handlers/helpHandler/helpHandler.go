@@ -0,0 +1,58 @@
+package helpHandler
+
+import (
+	"strings"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+)
+
+const handlerName = "HelpHandler"
+const command = "help"
+
+type HelpHandler struct {
+}
+
+func init() {
+	berghandler.RegisterHandler(HelpHandler{})
+	berghandler.RegisterMessages("de", berghandler.Messages{
+		"help.global.description": "Zeigt alle Befehle",
+	})
+	berghandler.RegisterMessages("en", berghandler.Messages{
+		"help.global.description": "Shows all commands",
+	})
+}
+
+func (h HelpHandler) GetName() string {
+	return handlerName
+}
+
+func (h HelpHandler) GetCommand() string {
+	return command
+}
+
+func (h HelpHandler) GetDescription() string {
+	return "help.global.description"
+}
+
+func (h HelpHandler) Prime(he berghandler.HandlerEssentials) error {
+	return nil
+}
+
+func (h HelpHandler) Handle(he berghandler.HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
+	if !berghandler.IsMessagewithPrefix(he, evt, command) {
+		return false
+	}
+	if strings.TrimSpace(strings.ToLower(evt.Content.AsMessage().Body)) != he.CommandPrefix()+command {
+		return false
+	}
+	handlers := he.Active
+	if len(handlers) == 0 {
+		for _, name := range berghandler.RegisteredHandlers() {
+			rh, _ := berghandler.GetHandler(name)
+			handlers = append(handlers, rh)
+		}
+	}
+	return berghandler.SendFormattedMessage(he, evt, handlerName, berghandler.RenderGlobalHelp(he, handlers))
+}
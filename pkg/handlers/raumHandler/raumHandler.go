@@ -0,0 +1,109 @@
+package raumHandler
+
+import (
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const handlerName = "RaumHandler"
+const command = "raum"
+
+type RaumHandler struct {
+	subHandlers berghandler.SubHandlers
+}
+
+func init() {
+	berghandler.RegisterHandler(&RaumHandler{})
+	berghandler.RegisterMessages("de", berghandler.Messages{
+		"raum.description": "Verwaltet die Räume des Bots",
+		"raum.help.join":   "Betritt einen Raum",
+		"raum.help.leave":  "Verlässt einen Raum, ohne Angabe den aktuellen",
+		"raum.help.list":   "Zeigt alle Räume in denen der Bot aktiv ist",
+		"raum.joined":      "Raum %v betreten",
+		"raum.joinerror":   "Fehler beim Betreten des Raums: %v",
+		"raum.left":        "Raum %v verlassen",
+		"raum.leaveerror":  "Fehler beim Verlassen des Raums: %v",
+		"raum.bye":         "Tschüss!",
+		"raum.list":        "Aktive Räume:",
+	})
+	berghandler.RegisterMessages("en", berghandler.Messages{
+		"raum.description": "Manages the rooms of the bot",
+		"raum.help.join":   "Joins a room",
+		"raum.help.leave":  "Leaves a room, the current one if none is given",
+		"raum.help.list":   "Shows all rooms the bot is active in",
+		"raum.joined":      "Joined room %v",
+		"raum.joinerror":   "Error joining the room: %v",
+		"raum.left":        "Left room %v",
+		"raum.leaveerror":  "Error leaving the room: %v",
+		"raum.bye":         "Bye!",
+		"raum.list":        "Active rooms:",
+	})
+}
+
+func (h *RaumHandler) Prime(he berghandler.HandlerEssentials) error {
+	h.subHandlers = make(map[string]berghandler.SubHandlerSet)
+	h.subHandlers["join"] = berghandler.SubHandlerSet{A: h.joinRoom, R: berghandler.RoleAdmin, H: "raum.help.join", E: []string{"#bestellungen:matrix.org"}, P: []berghandler.Param{
+		{Name: "Raum", Type: berghandler.ParamString, RawCase: true},
+	}}
+	h.subHandlers["leave"] = berghandler.SubHandlerSet{A: h.leaveRoom, R: berghandler.RoleAdmin, H: "raum.help.leave", P: []berghandler.Param{
+		{Name: "Raum", Type: berghandler.ParamString, Optional: true, RawCase: true},
+	}}
+	h.subHandlers["list"] = berghandler.SubHandlerSet{A: h.listRooms, R: berghandler.RoleAdmin, H: "raum.help.list"}
+	return nil
+}
+
+func (h *RaumHandler) GetName() string {
+	return handlerName
+}
+
+func (h *RaumHandler) GetCommand() string {
+	return command
+}
+
+func (h *RaumHandler) GetDescription() string {
+	return "raum.description"
+}
+
+func (h *RaumHandler) HasSubCommand(sub string) bool {
+	return h.subHandlers.Has(sub)
+}
+
+func (h *RaumHandler) Handle(he berghandler.HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
+	return h.subHandlers.Handle(command, handlerName, he, evt)
+}
+
+func (h *RaumHandler) joinRoom(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	roomID, err := he.Rooms.JoinRoom(args.String("Raum"))
+	if err != nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "raum.joinerror", err.Error()))
+	}
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "raum.joined", roomID))
+}
+
+func (h *RaumHandler) leaveRoom(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	roomID := evt.RoomID
+	if args.Has("Raum") {
+		roomID = id.RoomID(args.String("Raum"))
+	}
+	if roomID == evt.RoomID {
+		berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "raum.bye"))
+	}
+	err := he.Rooms.LeaveRoom(roomID)
+	if err != nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "raum.leaveerror", err.Error()))
+	}
+	if roomID == evt.RoomID {
+		return true
+	}
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "raum.left", roomID))
+}
+
+func (h *RaumHandler) listRooms(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	msg := berghandler.T(he, "raum.list") + "\n"
+	for _, r := range he.Rooms.JoinedRooms() {
+		msg += r.String() + "\n"
+	}
+	return berghandler.SendMessage(he, evt, handlerName, msg)
+}
@@ -0,0 +1,101 @@
+package spracheHandler
+
+import (
+	"strings"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+)
+
+const handlerName = "SpracheHandler"
+const command = "sprache"
+
+type SpracheHandler struct {
+	subHandlers berghandler.SubHandlers
+}
+
+func init() {
+	berghandler.RegisterHandler(&SpracheHandler{})
+	berghandler.RegisterMessages("de", berghandler.Messages{
+		"sprache.description": "Wählt die Sprache der Antworten / Choose the language of the answers",
+		"sprache.help.set":    "Setzt deine Sprache für alle Räume",
+		"sprache.help.reset":  "Entfernt deine Sprache, es gilt wieder die des Raums",
+		"sprache.help.show":   "Zeigt deine und die verfügbaren Sprachen",
+		"sprache.set":         "Sprache auf %v gesetzt",
+		"sprache.reset":       "Sprache entfernt, es gilt die Sprache des Raums (%v)",
+		"sprache.show":        "Aktuelle Sprache: %v, verfügbar: %v",
+		"sprache.error":       "Fehler beim Speichern der Sprache: %v",
+		"sprache.unavailable": "Sprachauswahl nicht verfügbar",
+	})
+	berghandler.RegisterMessages("en", berghandler.Messages{
+		"sprache.description": "Choose the language of the answers / Wählt die Sprache der Antworten",
+		"sprache.help.set":    "Sets your language for all rooms",
+		"sprache.help.reset":  "Removes your language, the one of the room applies again",
+		"sprache.help.show":   "Shows your language and the available ones",
+		"sprache.set":         "Language set to %v",
+		"sprache.reset":       "Language removed, the language of the room applies (%v)",
+		"sprache.show":        "Current language: %v, available: %v",
+		"sprache.error":       "Error saving the language: %v",
+		"sprache.unavailable": "Choosing a language is not available",
+	})
+}
+
+func (h *SpracheHandler) Prime(he berghandler.HandlerEssentials) error {
+	h.subHandlers = make(map[string]berghandler.SubHandlerSet)
+	h.subHandlers["set"] = berghandler.SubHandlerSet{A: h.setLanguage, H: "sprache.help.set", E: []string{"en"}, P: []berghandler.Param{
+		{Name: "Sprache", Type: berghandler.ParamEnum, Enum: berghandler.Languages()},
+	}}
+	h.subHandlers["reset"] = berghandler.SubHandlerSet{A: h.resetLanguage, H: "sprache.help.reset"}
+	h.subHandlers["show"] = berghandler.SubHandlerSet{A: h.showLanguage, H: "sprache.help.show"}
+	return nil
+}
+
+func (h *SpracheHandler) GetName() string {
+	return handlerName
+}
+
+func (h *SpracheHandler) GetCommand() string {
+	return command
+}
+
+func (h *SpracheHandler) GetDescription() string {
+	return "sprache.description"
+}
+
+func (h *SpracheHandler) HasSubCommand(sub string) bool {
+	return h.subHandlers.Has(sub)
+}
+
+func (h *SpracheHandler) Handle(he berghandler.HandlerEssentials, source mautrix.EventSource, evt *event.Event) bool {
+	return h.subHandlers.Handle(command, handlerName, he, evt)
+}
+
+func (h *SpracheHandler) setLanguage(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	if he.Languages == nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "sprache.unavailable"))
+	}
+	lang := args.String("Sprache")
+	err := he.Languages.SetUserLanguage(evt.Sender, lang)
+	if err != nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "sprache.error", err.Error()))
+	}
+	he.UserLanguage = lang
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "sprache.set", lang))
+}
+
+func (h *SpracheHandler) resetLanguage(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	if he.Languages == nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.T(he, "sprache.unavailable"))
+	}
+	err := he.Languages.SetUserLanguage(evt.Sender, "")
+	if err != nil {
+		return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "sprache.error", err.Error()))
+	}
+	he.UserLanguage = ""
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "sprache.reset", he.Language()))
+}
+
+func (h *SpracheHandler) showLanguage(he berghandler.HandlerEssentials, evt *event.Event, args berghandler.Args) bool {
+	return berghandler.SendMessage(he, evt, handlerName, berghandler.Tf(he, "sprache.show", he.Language(), strings.Join(berghandler.Languages(), ", ")))
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// syncState is what healthz and readyz report
+type syncState struct {
+	mu       sync.RWMutex
+	started  time.Time
+	lastOK   time.Time
+	failing  bool
+	lastErr  string
+	stopping bool
+}
+
+var state = syncState{started: time.Now()}
+
+// SyncSucceeded records a successful sync
+func SyncSucceeded() {
+	now := time.Now()
+	state.mu.Lock()
+	state.lastOK = now
+	state.failing = false
+	state.lastErr = ""
+	state.mu.Unlock()
+	lastSync.Set(float64(now.Unix()))
+}
+
+// SyncFailed records a failed sync, the bot is not ready until the next
+// successful one
+func SyncFailed(err error) {
+	state.mu.Lock()
+	state.failing = true
+	state.lastErr = err.Error()
+	state.mu.Unlock()
+	syncFailures.Inc()
+}
+
+// Stopping makes readyz fail while the bot shuts down
+func Stopping() {
+	state.mu.Lock()
+	state.stopping = true
+	state.mu.Unlock()
+}
+
+// healthz fails if there was no successful sync for longer than stale,
+// counted from the start before the first one
+func healthz(stale time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state.mu.RLock()
+		last := state.lastOK
+		if last.IsZero() {
+			last = state.started
+		}
+		lastErr := state.lastErr
+		state.mu.RUnlock()
+		if time.Since(last) > stale {
+			msg := "no successful sync since " + last.Format(time.RFC3339)
+			if lastErr != "" {
+				msg += ": " + lastErr
+			}
+			http.Error(w, msg, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	}
+}
+
+// readyz is ready after the first successful sync as long as the syncs keep
+// working
+func readyz(w http.ResponseWriter, r *http.Request) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	switch {
+	case state.stopping:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case state.lastOK.IsZero():
+		http.Error(w, "waiting for the first sync", http.StatusServiceUnavailable)
+	case state.failing:
+		http.Error(w, "sync failing: "+state.lastErr, http.StatusServiceUnavailable)
+	default:
+		w.Write([]byte("ok\n"))
+	}
+}
@@ -0,0 +1,189 @@
+// Package metrics exposes Prometheus metrics and health endpoints of the bot
+// on an optional HTTP listener.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const namespace = "bergknecht"
+const defaultPath = "/metrics"
+const defaultStaleSeconds = 300
+const shutdownTimeout = 5 * time.Second
+
+// maxSubLabels limits the sub-command label per handler, the sub-command is
+// user input and everything after the limit is counted as "other"
+const maxSubLabels = 50
+
+type Config struct {
+	Enabled      bool
+	Listen       string //Address of the listener, e.g. ":9090"
+	Path         string //Path of the metrics, default /metrics
+	StaleSeconds int    //healthz fails if the last successful sync is older, default 300
+}
+
+var registry = prometheus.NewRegistry()
+
+var (
+	eventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "events_received_total", Help: "Events received from the sync by type",
+	}, []string{"type"})
+	commands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "commands_total", Help: "Handled commands by handler and sub-command",
+	}, []string{"handler", "sub"})
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "handler_duration_seconds", Help: "Duration of handled events by handler",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"handler"})
+	sendErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "send_errors_total", Help: "Failed send attempts by Matrix error code",
+	}, []string{"code"})
+	syncFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "sync_failures_total", Help: "Failed syncs",
+	})
+	lastSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "last_sync_timestamp_seconds", Help: "Time of the last successful sync",
+	})
+)
+
+func init() {
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	registry.MustRegister(eventsReceived, commands, handlerDuration, sendErrors, syncFailures, lastSync)
+}
+
+// MustRegister adds collectors of handlers, it panics on duplicates like
+// prometheus.MustRegister
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+func EventReceived(evt *event.Event) {
+	eventsReceived.WithLabelValues(evt.Type.Type).Inc()
+}
+
+type subLabels struct {
+	mu   sync.Mutex
+	seen map[string]map[string]bool
+}
+
+func (sl *subLabels) label(handler, sub string) string {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	subs, ex := sl.seen[handler]
+	if !ex {
+		subs = make(map[string]bool)
+		sl.seen[handler] = subs
+	}
+	if !subs[sub] && len(subs) >= maxSubLabels {
+		return "other"
+	}
+	subs[sub] = true
+	return sub
+}
+
+// Middleware counts the commands and measures the handled events
+func Middleware() berghandler.Middleware {
+	sl := &subLabels{seen: make(map[string]map[string]bool)}
+	return berghandler.After(func(he berghandler.HandlerEssentials, inv *berghandler.Invocation, handled bool, d time.Duration) {
+		if !handled {
+			return
+		}
+		name := inv.Handler.GetName()
+		handlerDuration.WithLabelValues(name).Observe(d.Seconds())
+		if inv.IsCommand() {
+			commands.WithLabelValues(name, sl.label(name, inv.Sub)).Inc()
+		}
+	})
+}
+
+// Client counts the failed sends of the wrapped client, below the outbound
+// queue every failed attempt is counted.
+type Client struct {
+	berghandler.MatrixClient
+}
+
+func countSend(resp *mautrix.RespSendEvent, err error) (*mautrix.RespSendEvent, error) {
+	if err != nil {
+		code := "unknown"
+		var httpErr mautrix.HTTPError
+		if errors.As(err, &httpErr) {
+			if httpErr.RespError != nil && httpErr.RespError.ErrCode != "" {
+				code = httpErr.RespError.ErrCode
+			} else if httpErr.Response != nil {
+				code = "HTTP " + strconv.Itoa(httpErr.Response.StatusCode)
+			} else {
+				code = "connection"
+			}
+		}
+		sendErrors.WithLabelValues(code).Inc()
+	}
+	return resp, err
+}
+
+func (c Client) SendText(roomID id.RoomID, text string) (*mautrix.RespSendEvent, error) {
+	return countSend(c.MatrixClient.SendText(roomID, text))
+}
+
+func (c Client) SendMessageEvent(roomID id.RoomID, eventType event.Type, contentJSON interface{}, extra ...mautrix.ReqSendEvent) (*mautrix.RespSendEvent, error) {
+	return countSend(c.MatrixClient.SendMessageEvent(roomID, eventType, contentJSON, extra...))
+}
+
+func (c Client) SendStateEvent(roomID id.RoomID, eventType event.Type, stateKey string, contentJSON interface{}) (*mautrix.RespSendEvent, error) {
+	return countSend(c.MatrixClient.SendStateEvent(roomID, eventType, stateKey, contentJSON))
+}
+
+func (c Client) SendReaction(roomID id.RoomID, eventID id.EventID, reaction string) (*mautrix.RespSendEvent, error) {
+	return countSend(c.MatrixClient.SendReaction(roomID, eventID, reaction))
+}
+
+func (c Client) RedactEvent(roomID id.RoomID, eventID id.EventID, extra ...mautrix.ReqRedact) (*mautrix.RespSendEvent, error) {
+	return countSend(c.MatrixClient.RedactEvent(roomID, eventID, extra...))
+}
+
+// Serve runs the listener until ctx is cancelled, it does nothing if the
+// metrics are disabled.
+func Serve(ctx context.Context, c Config, logger *zap.SugaredLogger) error {
+	if !c.Enabled {
+		return nil
+	}
+	path := c.Path
+	if path == "" {
+		path = defaultPath
+	}
+	stale := time.Duration(c.StaleSeconds) * time.Second
+	if stale <= 0 {
+		stale = defaultStaleSeconds * time.Second
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", healthz(stale))
+	mux.HandleFunc("/readyz", readyz)
+	server := &http.Server{Addr: c.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(sctx)
+	}()
+	logger.Infow("Serving metrics", "listen", c.Listen, "path", path)
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.New("Error serving metrics: " + err.Error())
+	}
+	return nil
+}
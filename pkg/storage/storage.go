@@ -46,6 +46,15 @@ func (sm *Manager) getFilenameandPath(Handlername, Filename string, persitent bo
 	return fullpath, path
 }
 
+func (sm *Manager) GetPath(Handlername, Filename string, persitent bool) (string, error) {
+	fullpath, path := sm.getFilenameandPath(Handlername, Filename, persitent)
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return "", errors.New("Error creating directory: " + err.Error())
+	}
+	return fullpath, nil
+}
+
 func (sm *Manager) DoesFileExist(Handlername, Filename string, persitent bool) bool {
 	fullpath, _ := sm.getFilenameandPath(Handlername, Filename, persitent)
 	if _, err := os.Stat(fullpath); err == nil {
@@ -83,6 +92,26 @@ func (sm *Manager) DeleteFile(Handlername, Filename string, persitent bool) erro
 	return os.Remove(fullpath)
 }
 
+// ListFiles returns the names of the files of a handler, none if it has no
+// directory yet
+func (sm *Manager) ListFiles(Handlername string, persitent bool) ([]string, error) {
+	_, path := sm.getFilenameandPath(Handlername, "", persitent)
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("Error reading directory: " + err.Error())
+	}
+	var result []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			result = append(result, e.Name())
+		}
+	}
+	return result, nil
+}
+
 func (sm *Manager) EncodeFile(Handlername, Filename string, FileType FileType, persistent bool, v interface{}) error {
 	switch FileType {
 	case TOML:
@@ -159,8 +188,10 @@ func (sm *Manager) decodeJSONFile(Handlername, Filename string, persistent bool,
 	return nil
 }
 
+// DeleteCache removes all cached files on shutdown, everything that has to
+// survive a restart belongs into the persistent storage
 func (sm *Manager) DeleteCache() error {
-	err := os.Remove(sm.cachedPath)
+	err := os.RemoveAll(sm.cachedPath)
 	if err != nil {
 		return errors.New("Error removing cached Files: " + err.Error())
 	}
@@ -0,0 +1,391 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"maunium.net/go/mautrix/id"
+)
+
+// maxCommits is the number of commits listed for a push, the rest is linked
+const maxCommits = 5
+
+// forge lists the headers of a git hosting, Gitea also sends the GitHub ones
+type forge struct {
+	eventHeaders     []string
+	signatureHeaders []string
+}
+
+var forges = map[string]forge{
+	"gitea": {
+		eventHeaders:     []string{"X-Gitea-Event", "X-GitHub-Event"},
+		signatureHeaders: []string{"X-Gitea-Signature", "X-Hub-Signature-256"},
+	},
+	"github": {
+		eventHeaders:     []string{"X-GitHub-Event"},
+		signatureHeaders: []string{"X-Hub-Signature-256"},
+	},
+}
+
+func init() {
+	berghandler.RegisterMessages("de", berghandler.Messages{
+		"git.push.one":       "%v hat 1 Commit nach %v gepusht",
+		"git.push":           "%v hat %v Commits nach %v gepusht",
+		"git.push.more":      "… und %v weitere",
+		"git.issue.opened":   "%v hat Issue %v geöffnet: %v",
+		"git.issue.closed":   "%v hat Issue %v geschlossen: %v",
+		"git.issue.reopened": "%v hat Issue %v wieder geöffnet: %v",
+		"git.pr.opened":      "%v hat Pull Request %v geöffnet: %v",
+		"git.pr.closed":      "%v hat Pull Request %v geschlossen: %v",
+		"git.pr.merged":      "%v hat Pull Request %v gemergt: %v",
+		"git.pr.reopened":    "%v hat Pull Request %v wieder geöffnet: %v",
+		"git.release":        "%v hat Release %v veröffentlicht",
+		"git.release.pre":    "%v hat Vorabversion %v veröffentlicht",
+	})
+	berghandler.RegisterMessages("en", berghandler.Messages{
+		"git.push.one":       "%v pushed 1 commit to %v",
+		"git.push":           "%v pushed %v commits to %v",
+		"git.push.more":      "… and %v more",
+		"git.issue.opened":   "%v opened issue %v: %v",
+		"git.issue.closed":   "%v closed issue %v: %v",
+		"git.issue.reopened": "%v reopened issue %v: %v",
+		"git.pr.opened":      "%v opened pull request %v: %v",
+		"git.pr.closed":      "%v closed pull request %v: %v",
+		"git.pr.merged":      "%v merged pull request %v: %v",
+		"git.pr.reopened":    "%v reopened pull request %v: %v",
+		"git.release":        "%v published release %v",
+		"git.release.pre":    "%v published pre-release %v",
+	})
+}
+
+type gitUser struct {
+	Login    string `json:"login"`
+	Username string `json:"username"` //Gitea commit authors
+	Name     string `json:"name"`
+}
+
+func (u gitUser) display() string {
+	switch {
+	case u.Login != "":
+		return u.Login
+	case u.Username != "":
+		return u.Username
+	}
+	return u.Name
+}
+
+type gitRepository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type gitCommit struct {
+	ID      string  `json:"id"`
+	Message string  `json:"message"`
+	URL     string  `json:"url"`
+	Author  gitUser `json:"author"`
+}
+
+type pushPayload struct {
+	Ref        string      `json:"ref"`
+	Compare    string      `json:"compare"`     //GitHub
+	CompareURL string      `json:"compare_url"` //Gitea
+	Deleted    bool        `json:"deleted"`
+	Commits    []gitCommit `json:"commits"`
+	Pusher     gitUser     `json:"pusher"`
+	Sender     gitUser     `json:"sender"`
+}
+
+type gitIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	Merged  bool   `json:"merged"`
+}
+
+type issuePayload struct {
+	Action      string   `json:"action"`
+	Issue       gitIssue `json:"issue"`
+	PullRequest gitIssue `json:"pull_request"`
+	Sender      gitUser  `json:"sender"`
+}
+
+type releasePayload struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		HTMLURL    string `json:"html_url"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+	Sender gitUser `json:"sender"`
+}
+
+// part is a piece of a summary, as HTML and as plain text
+type part struct {
+	html  string
+	plain string
+}
+
+func textPart(text string) part {
+	return part{html: html.EscapeString(text), plain: text}
+}
+
+func boldPart(text string) part {
+	return part{html: "<b>" + html.EscapeString(text) + "</b>", plain: text}
+}
+
+func codePart(text string) part {
+	return part{html: "<code>" + html.EscapeString(text) + "</code>", plain: text}
+}
+
+func linkPart(p part, url string) part {
+	if url == "" {
+		return p
+	}
+	return part{html: `<a href="` + html.EscapeString(url) + `">` + p.html + "</a>", plain: p.plain}
+}
+
+// sprintf fills the message with the parts, the message itself is text
+func sprintf(msg string, parts ...part) part {
+	var htmls, plains []interface{}
+	for _, p := range parts {
+		htmls = append(htmls, p.html)
+		plains = append(plains, p.plain)
+	}
+	return part{html: fmt.Sprintf(html.EscapeString(msg), htmls...), plain: fmt.Sprintf(msg, plains...)}
+}
+
+// summary is the repository in brackets followed by line
+func summary(repo gitRepository, line part, list []part) berghandler.Formatted {
+	head := sprintf("[%v] %v", linkPart(textPart(repo.FullName), repo.HTMLURL), line)
+	res := berghandler.Formatted{HTML: "<p>" + head.html + "</p>", Plain: head.plain}
+	if len(list) == 0 {
+		return res
+	}
+	res.HTML += "<ul>"
+	for _, l := range list {
+		res.HTML += "<li>" + l.html + "</li>"
+		res.Plain += "\n- " + l.plain
+	}
+	res.HTML += "</ul>"
+	return res
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+}
+
+func shortID(commitID string) string {
+	if len(commitID) > 7 {
+		return commitID[:7]
+	}
+	return commitID
+}
+
+func summarizePush(he berghandler.HandlerEssentials, repo gitRepository, body []byte) (berghandler.Formatted, bool, error) {
+	var p pushPayload
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return berghandler.Formatted{}, false, err
+	}
+	// Deleted branches, tags and branches without new commits are not worth a message
+	if p.Deleted || len(p.Commits) == 0 || !strings.HasPrefix(p.Ref, "refs/heads/") {
+		return berghandler.Formatted{}, false, nil
+	}
+	pusher := p.Pusher.display()
+	if p.Sender.display() != "" {
+		pusher = p.Sender.display()
+	}
+	compare := p.Compare
+	if compare == "" {
+		compare = p.CompareURL
+	}
+	branch := codePart(strings.TrimPrefix(p.Ref, "refs/heads/"))
+	var line part
+	if len(p.Commits) == 1 {
+		line = sprintf(berghandler.T(he, "git.push.one"), boldPart(pusher), branch)
+	} else {
+		line = sprintf(berghandler.T(he, "git.push"), boldPart(pusher), textPart(fmt.Sprint(len(p.Commits))), branch)
+	}
+	line = linkPart(line, compare)
+	var list []part
+	for i, c := range p.Commits {
+		if i == maxCommits {
+			list = append(list, linkPart(textPart(berghandler.Tf(he, "git.push.more", len(p.Commits)-maxCommits)), compare))
+			break
+		}
+		list = append(list, sprintf("%v %v (%v)", linkPart(codePart(shortID(c.ID)), c.URL), textPart(firstLine(c.Message)), textPart(c.Author.display())))
+	}
+	return summary(repo, line, list), true, nil
+}
+
+func summarizeIssue(he berghandler.HandlerEssentials, repo gitRepository, body []byte, pull bool) (berghandler.Formatted, bool, error) {
+	var p issuePayload
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return berghandler.Formatted{}, false, err
+	}
+	issue, prefix := p.Issue, "git.issue."
+	if pull {
+		issue, prefix = p.PullRequest, "git.pr."
+	}
+	action := p.Action
+	if action == "closed" && issue.Merged {
+		action = "merged"
+	}
+	// Labels, assignees, edits and the like are left out to keep the room readable
+	switch action {
+	case "opened", "closed", "reopened":
+	case "merged":
+		if !pull {
+			return berghandler.Formatted{}, false, nil
+		}
+	default:
+		return berghandler.Formatted{}, false, nil
+	}
+	number := linkPart(textPart(fmt.Sprintf("#%d", issue.Number)), issue.HTMLURL)
+	line := sprintf(berghandler.T(he, prefix+action), boldPart(p.Sender.display()), number, textPart(issue.Title))
+	return summary(repo, line, nil), true, nil
+}
+
+func summarizeRelease(he berghandler.HandlerEssentials, repo gitRepository, body []byte) (berghandler.Formatted, bool, error) {
+	var p releasePayload
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return berghandler.Formatted{}, false, err
+	}
+	if p.Action != "published" {
+		return berghandler.Formatted{}, false, nil
+	}
+	name := p.Release.Name
+	if name == "" {
+		name = p.Release.TagName
+	}
+	key := "git.release"
+	if p.Release.Prerelease {
+		key = "git.release.pre"
+	}
+	line := sprintf(berghandler.T(he, key), boldPart(p.Sender.display()), linkPart(textPart(name), p.Release.HTMLURL))
+	return summary(repo, line, nil), true, nil
+}
+
+// Summarize turns a push, issues, pull_request or release payload into a
+// message, ok is false for events and actions that are not posted. It only
+// needs the event name and the body, so recorded payloads can be fed in.
+func Summarize(he berghandler.HandlerEssentials, eventName string, body []byte) (repo string, msg berghandler.Formatted, ok bool, err error) {
+	var base struct {
+		Repository gitRepository `json:"repository"`
+	}
+	err = json.Unmarshal(body, &base)
+	if err != nil {
+		return "", msg, false, errors.New("Error decoding payload: " + err.Error())
+	}
+	switch eventName {
+	case "push":
+		msg, ok, err = summarizePush(he, base.Repository, body)
+	case "issues":
+		msg, ok, err = summarizeIssue(he, base.Repository, body, false)
+	case "pull_request":
+		msg, ok, err = summarizeIssue(he, base.Repository, body, true)
+	case "release":
+		msg, ok, err = summarizeRelease(he, base.Repository, body)
+	}
+	if err != nil {
+		return "", msg, false, errors.New("Error decoding " + eventName + " payload: " + err.Error())
+	}
+	return base.Repository.FullName, msg, ok, nil
+}
+
+// gitReceiver posts summaries of Gitea or GitHub events into the room of the
+// repository
+type gitReceiver struct {
+	hook  Hook
+	forge forge
+	rooms map[string]id.RoomID //Per lower case repository name
+}
+
+func newGitReceiver(h Hook) (*gitReceiver, error) {
+	if h.Token == "" {
+		return nil, errors.New("no token set, it is the secret of the webhook")
+	}
+	if h.Room == "" && len(h.Rooms) == 0 {
+		return nil, errors.New("neither room nor rooms set")
+	}
+	if h.Language != "" && !berghandler.HasLanguage(h.Language) {
+		return nil, errors.New("unknown language " + h.Language)
+	}
+	res := &gitReceiver{hook: h, forge: forges[h.Type], rooms: make(map[string]id.RoomID)}
+	for repo, room := range h.Rooms {
+		res.rooms[strings.ToLower(repo)] = id.RoomID(room)
+	}
+	return res, nil
+}
+
+func firstHeader(r *http.Request, names []string) string {
+	for _, n := range names {
+		if v := r.Header.Get(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// verify checks the HMAC-SHA256 of the body, Gitea sends it as hex and
+// GitHub prefixed with sha256=
+func (gr *gitReceiver) verify(r *http.Request, body []byte) error {
+	sig := firstHeader(r, gr.forge.signatureHeaders)
+	if sig == "" {
+		return errors.New("missing signature")
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(gr.hook.Token))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (gr *gitReceiver) room(repo string) id.RoomID {
+	if room, ex := gr.rooms[strings.ToLower(repo)]; ex {
+		return room
+	}
+	return id.RoomID(gr.hook.Room)
+}
+
+func (gr *gitReceiver) Receive(he berghandler.HandlerEssentials, r *http.Request, body []byte) (int, error) {
+	err := gr.verify(r, body)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	eventName := firstHeader(r, gr.forge.eventHeaders)
+	if eventName == "" {
+		return http.StatusBadRequest, errors.New("missing event header")
+	}
+	he.Room.Language = gr.hook.Language
+	repo, msg, ok, err := Summarize(he, eventName, body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	roomID := gr.room(repo)
+	if !ok || roomID == "" {
+		return http.StatusNoContent, nil
+	}
+	err = berghandler.SendToRoom(he, roomID, handlerName, msg)
+	if err != nil {
+		return http.StatusBadGateway, errors.New("Error sending message: " + err.Error())
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler/bergtest"
+	"go.uber.org/zap"
+	"maunium.net/go/mautrix/id"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSummarizeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		event    string
+		lang     string
+		wantRepo string
+		wantOK   bool
+		want     string //Plain text
+		wantHTML string //Part of the HTML
+	}{
+		{
+			fixture: "gitea/push.json", event: "push", wantRepo: "nerdberg/bergknecht", wantOK: true,
+			want:     "[nerdberg/bergknecht] alex hat 2 Commits nach main gepusht\n- 1a2b3c4 Add webhook server (alex)\n- 9f2c1e7 Escape <html> in order names (kim)",
+			wantHTML: `<code>9f2c1e7</code></a> Escape &lt;html&gt; in order names (kim)`,
+		},
+		{
+			fixture: "gitea/issues.json", event: "issues", wantRepo: "nerdberg/bergknecht", wantOK: true,
+			want:     "[nerdberg/bergknecht] kim hat Issue #42 geöffnet: Bestellung schließt nicht nach Timeout",
+			wantHTML: `<a href="https://git.nerdberg.de/nerdberg/bergknecht/issues/42">#42</a>`,
+		},
+		{
+			fixture: "gitea/pull_request.json", event: "pull_request", wantRepo: "nerdberg/bergknecht", wantOK: true,
+			want:     "[nerdberg/bergknecht] kim hat Pull Request #43 gemergt: Gitea Webhooks",
+			wantHTML: `<b>kim</b> hat Pull Request`,
+		},
+		{
+			fixture: "gitea/release.json", event: "release", wantRepo: "nerdberg/bergknecht", wantOK: true,
+			want:     "[nerdberg/bergknecht] alex hat Release Bergknecht 1.4 veröffentlicht",
+			wantHTML: `<a href="https://git.nerdberg.de/nerdberg/bergknecht/releases/tag/v1.4.0">Bergknecht 1.4</a>`,
+		},
+		{
+			fixture: "gitea/issues.json", event: "issues", lang: "en", wantRepo: "nerdberg/bergknecht", wantOK: true,
+			want: "[nerdberg/bergknecht] kim opened issue #42: Bestellung schließt nicht nach Timeout",
+		},
+		{
+			fixture: "github/push.json", event: "push", lang: "en", wantRepo: "Nerdbergev/Bergknecht", wantOK: true,
+			want:     "[Nerdbergev/Bergknecht] alex-nb pushed 1 commit to master\n- b6568db Update README (alex-nb)",
+			wantHTML: `<a href="https://github.com/Nerdbergev/Bergknecht/compare/0d1a26e67d8f...b6568db1bc1d">`,
+		},
+		{fixture: "gitea/release.json", event: "create", wantRepo: "nerdberg/bergknecht"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture+" "+tt.event+" "+tt.lang, func(t *testing.T) {
+			he := berghandler.HandlerEssentials{Room: berghandler.RoomSettings{Language: tt.lang}}
+			repo, msg, ok, err := Summarize(he, tt.event, readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo != tt.wantRepo || ok != tt.wantOK {
+				t.Fatalf("Summarize() repo = %q, ok = %v, want %q, %v", repo, ok, tt.wantRepo, tt.wantOK)
+			}
+			if msg.Plain != tt.want {
+				t.Errorf("plain text =\n%v\nwant\n%v", msg.Plain, tt.want)
+			}
+			if !strings.Contains(msg.HTML, tt.wantHTML) {
+				t.Errorf("HTML = %v, want it to contain %v", msg.HTML, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestSummarizeSkips(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		body  string
+	}{
+		{"deleted branch", "push", `{"ref":"refs/heads/old","deleted":true,"commits":[{"id":"1"}]}`},
+		{"tag", "push", `{"ref":"refs/tags/v1.0","commits":[{"id":"1"}]}`},
+		{"no commits", "push", `{"ref":"refs/heads/main","commits":[]}`},
+		{"labeled issue", "issues", `{"action":"labeled","issue":{"number":1}}`},
+		{"merged issue", "issues", `{"action":"closed","issue":{"number":1,"merged":true}}`},
+		{"draft release", "release", `{"action":"created","release":{"tag_name":"v1"}}`},
+	}
+	for _, tt := range tests {
+		_, _, ok, err := Summarize(berghandler.HandlerEssentials{}, tt.event, []byte(tt.body))
+		if err != nil || ok {
+			t.Errorf("%v: Summarize() ok = %v, err = %v, want it skipped", tt.name, ok, err)
+		}
+	}
+	_, _, _, err := Summarize(berghandler.HandlerEssentials{}, "push", []byte("no json"))
+	if err == nil {
+		t.Error("Summarize() accepted a body that is no JSON")
+	}
+}
+
+func TestSummarizeManyCommits(t *testing.T) {
+	body := `{"ref":"refs/heads/main","compare":"https://example.org/compare","repository":{"full_name":"a/b"},"sender":{"login":"x"},"commits":[`
+	for i := 0; i < maxCommits+2; i++ {
+		if i > 0 {
+			body += ","
+		}
+		body += `{"id":"abcdef0123","message":"commit","author":{"name":"x"}}`
+	}
+	body += "]}"
+	_, msg, ok, err := Summarize(berghandler.HandlerEssentials{}, "push", []byte(body))
+	if err != nil || !ok {
+		t.Fatalf("Summarize() ok = %v, err = %v", ok, err)
+	}
+	if n := strings.Count(msg.Plain, "\n- abcdef0 commit"); n != maxCommits {
+		t.Errorf("%v commits listed, want %v", n, maxCommits)
+	}
+	if !strings.HasSuffix(msg.Plain, "\n- … und 2 weitere") {
+		t.Errorf("plain text %q does not end with the remaining commits", msg.Plain)
+	}
+}
+
+func TestGitSignature(t *testing.T) {
+	body := readFixture(t, "gitea/push.json")
+	tests := []struct {
+		name   string
+		forge  string
+		header string
+		value  string
+		want   int
+	}{
+		{"gitea hex", "gitea", "X-Gitea-Signature", sign("geheim", body), http.StatusOK},
+		{"gitea github header", "gitea", "X-Hub-Signature-256", "sha256=" + sign("geheim", body), http.StatusOK},
+		{"gitea wrong secret", "gitea", "X-Gitea-Signature", sign("falsch", body), http.StatusUnauthorized},
+		{"gitea malformed", "gitea", "X-Gitea-Signature", "zz", http.StatusUnauthorized},
+		{"gitea missing", "gitea", "", "", http.StatusUnauthorized},
+		{"github", "github", "X-Hub-Signature-256", "sha256=" + sign("geheim", body), http.StatusOK},
+		{"github wrong secret", "github", "X-Hub-Signature-256", "sha256=" + sign("falsch", body), http.StatusUnauthorized},
+		{"github ignores gitea header", "github", "X-Gitea-Signature", sign("geheim", body), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := bergtest.NewFakeClient()
+			he := berghandler.HandlerEssentials{Client: fc, Logger: zap.NewNop().Sugar()}
+			s, err := NewServer(Config{Hooks: map[string]Hook{
+				"git": {Type: tt.forge, Token: "geheim", Room: "!default:test", Rooms: map[string]string{"Nerdberg/Bergknecht": "!berg:test"}},
+			}}, he)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/hooks/git", bytes.NewReader(body))
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			r.Header.Set("X-GitHub-Event", "push")
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %v, want %v: %v", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if len(fc.Sent) != 0 {
+					t.Errorf("sent %v messages for a rejected request", len(fc.Sent))
+				}
+				return
+			}
+			if len(fc.Sent) != 1 || fc.Sent[0].RoomID != id.RoomID("!berg:test") {
+				t.Errorf("sent %+v, want one message to the room of the repository", fc.Sent)
+			}
+		})
+	}
+}
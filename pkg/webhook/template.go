@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"maunium.net/go/mautrix/id"
+)
+
+// templateData is what the template of a hook gets
+type templateData struct {
+	Hook    string
+	Payload interface{} //Decoded JSON body, the body as string if it is no JSON
+	Body    string
+	Header  http.Header //Without the credentials
+	Query   url.Values  //Without the token
+}
+
+// secretHeaders are dropped from the template data, a template must not be
+// able to post the token into a room
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Bergknecht-Token"}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// executor is a parsed text or html template
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// templateReceiver posts the rendered template into the room of the hook
+type templateReceiver struct {
+	name   string
+	hook   Hook
+	roomID id.RoomID
+	tmpl   executor
+}
+
+func loadTemplate(he berghandler.HandlerEssentials, h Hook) (string, error) {
+	if h.TemplateFile == "" {
+		return h.Template, nil
+	}
+	f, err := he.Storage.GetFileReading(handlerName, h.TemplateFile, true)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", errors.New("Error reading template: " + err.Error())
+	}
+	return string(b), nil
+}
+
+func newTemplateReceiver(he berghandler.HandlerEssentials, name string, h Hook) (*templateReceiver, error) {
+	if h.Token == "" {
+		return nil, errors.New("no token set")
+	}
+	if h.Room == "" {
+		return nil, errors.New("no room set")
+	}
+	switch h.Format {
+	case "", "markdown", "html", "text":
+	default:
+		return nil, errors.New("unknown format " + h.Format + ", use markdown, html or text")
+	}
+	text, err := loadTemplate(he, h)
+	if err != nil {
+		return nil, err
+	}
+	// HTML goes into the room as it is, so the values of the request are
+	// escaped there, the other formats escape on their own
+	var tmpl executor
+	if h.Format == "html" {
+		tmpl, err = htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+	} else {
+		tmpl, err = template.New(name).Funcs(templateFuncs).Parse(text)
+	}
+	if err != nil {
+		return nil, errors.New("Error parsing template: " + err.Error())
+	}
+	return &templateReceiver{name: name, hook: h, roomID: id.RoomID(h.Room), tmpl: tmpl}, nil
+}
+
+func requestToken(r *http.Request) string {
+	if t := r.Header.Get("X-Bergknecht-Token"); t != "" {
+		return t
+	}
+	if a := r.Header.Get("Authorization"); strings.HasPrefix(a, "Bearer ") {
+		return strings.TrimPrefix(a, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// publicRequest returns the header and the query of r without credentials
+func publicRequest(r *http.Request) (http.Header, url.Values) {
+	header := r.Header.Clone()
+	for _, h := range secretHeaders {
+		header.Del(h)
+	}
+	query := r.URL.Query()
+	query.Del("token")
+	return header, query
+}
+
+func (tr *templateReceiver) Receive(he berghandler.HandlerEssentials, r *http.Request, body []byte) (int, error) {
+	if subtle.ConstantTimeCompare([]byte(requestToken(r)), []byte(tr.hook.Token)) != 1 {
+		return http.StatusUnauthorized, errors.New("invalid token")
+	}
+	data := templateData{Hook: tr.name, Body: string(body)}
+	data.Header, data.Query = publicRequest(r)
+	err := json.Unmarshal(body, &data.Payload)
+	if err != nil {
+		data.Payload = string(body)
+	}
+	var buf bytes.Buffer
+	err = tr.tmpl.Execute(&buf, data)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("Error rendering template: " + err.Error())
+	}
+	msg := strings.TrimSpace(buf.String())
+	if msg == "" {
+		// The template decided there is nothing to say
+		return http.StatusNoContent, nil
+	}
+	var f berghandler.Formatted
+	switch tr.hook.Format {
+	case "html":
+		f = berghandler.HTML(msg)
+	case "text":
+		f = berghandler.Text(msg)
+	default:
+		f = berghandler.Markdown(msg)
+	}
+	err = berghandler.SendToRoom(he, tr.roomID, handlerName, f)
+	if err != nil {
+		return http.StatusBadGateway, errors.New("Error sending message: " + err.Error())
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler/bergtest"
+	"go.uber.org/zap"
+)
+
+func TestTemplateHidesToken(t *testing.T) {
+	fc := bergtest.NewFakeClient()
+	he := berghandler.HandlerEssentials{Client: fc, Logger: zap.NewNop().Sugar()}
+	s, err := NewServer(Config{Hooks: map[string]Hook{
+		"leak": {Token: "geheim", Room: "!room:test", Format: "text", Template: "{{.Header}} {{.Query}} {{.Payload.msg}}"},
+	}}, he)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"/hooks/leak?token=geheim&x=1", "/hooks/leak?x=1"} {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"msg":"hallo"}`))
+		r.Header.Set("X-Bergknecht-Token", "geheim")
+		r.Header.Set("Authorization", "Bearer geheim")
+		r.Header.Set("Cookie", "session=geheim")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %v: %v", w.Code, w.Body.String())
+		}
+	}
+	msgs := fc.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("sent %v messages, want 2", len(msgs))
+	}
+	for _, m := range msgs {
+		if strings.Contains(m.Body, "geheim") {
+			t.Errorf("message contains the token: %v", m.Body)
+		}
+		if !strings.Contains(m.Body, "hallo") || !strings.Contains(m.Body, "x:[1]") {
+			t.Errorf("message lost the payload or the query: %v", m.Body)
+		}
+	}
+}
+
+func TestTemplateEscapesPayload(t *testing.T) {
+	payload := `{"msg":"<a href=\"https://evil.example\">klick</a>"}`
+	tests := []struct {
+		format   string
+		template string
+		wantHTML string
+	}{
+		{"html", "<b>{{.Payload.msg}}</b>", "<b>&lt;a href=&#34;https://evil.example&#34;&gt;klick&lt;/a&gt;</b>"},
+		{"html", `<a href="{{.Query.Get "next"}}">weiter</a>`, `<a href="#ZgotmplZ">weiter</a>`},
+		{"markdown", "**{{.Payload.msg}}**", "<strong>&lt;a href="},
+	}
+	for _, tt := range tests {
+		fc := bergtest.NewFakeClient()
+		he := berghandler.HandlerEssentials{Client: fc, Logger: zap.NewNop().Sugar()}
+		s, err := NewServer(Config{Hooks: map[string]Hook{
+			"inject": {Token: "geheim", Room: "!room:test", Format: tt.format, Template: tt.template},
+		}}, he)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/hooks/inject?next=javascript:alert(1)", strings.NewReader(payload))
+		r.Header.Set("X-Bergknecht-Token", "geheim")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: status = %v: %v", tt.template, w.Code, w.Body.String())
+		}
+		msgs := fc.Messages()
+		if len(msgs) != 1 {
+			t.Fatalf("%v: sent %v messages, want 1", tt.template, len(msgs))
+		}
+		if !strings.Contains(msgs[0].FormattedBody, tt.wantHTML) {
+			t.Errorf("%v: HTML = %v, want it to contain %v", tt.template, msgs[0].FormattedBody, tt.wantHTML)
+		}
+		if strings.Contains(msgs[0].FormattedBody, "https://evil.example\"") || strings.Contains(msgs[0].FormattedBody, "javascript:") {
+			t.Errorf("%v: HTML = %v, the payload got into the markup", tt.template, msgs[0].FormattedBody)
+		}
+	}
+}
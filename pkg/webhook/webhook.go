@@ -0,0 +1,130 @@
+// Package webhook runs an HTTP server that lets other systems post into
+// Matrix rooms through the bot. Every configured hook is reachable under
+// /hooks/<name>.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nerdbergev/Bergknecht/pkg/berghandler"
+)
+
+const handlerName = "Webhooks"
+const hookPath = "/hooks/"
+const defaultMaxBodyBytes = 1 << 20
+const shutdownTimeout = 5 * time.Second
+
+type Config struct {
+	Enabled      bool
+	Listen       string          //Address of the listener, e.g. ":8080"
+	MaxBodyBytes int64           //Larger requests are rejected, default 1 MiB
+	Hooks        map[string]Hook //Per hook name
+}
+
+type Hook struct {
+	Type         string            //template (default), gitea or github
+	Token        string            //Secret, as X-Bergknecht-Token header, Bearer token or ?token=, for gitea and github the signing secret
+	Room         string            //Room ID the messages go to
+	Template     string            //Go template rendered with the payload
+	TemplateFile string            //Template in the persistent storage of Webhooks, instead of Template
+	Format       string            //markdown (default), html or text
+	Rooms        map[string]string //gitea and github: Room per repository, e.g. "nerdberg/bergknecht", the others go to Room
+	Language     string            //gitea and github: Language of the summaries
+}
+
+// Receiver processes the requests of one hook, it returns the HTTP status
+// and an error that is reported to the caller.
+type Receiver interface {
+	Receive(he berghandler.HandlerEssentials, r *http.Request, body []byte) (int, error)
+}
+
+type Server struct {
+	conf      Config
+	he        berghandler.HandlerEssentials
+	receivers map[string]Receiver
+}
+
+// NewServer prepares the receivers of all hooks, a broken hook fails here and
+// not on its first request.
+func NewServer(c Config, he berghandler.HandlerEssentials) (*Server, error) {
+	res := new(Server)
+	res.conf = c
+	if res.conf.MaxBodyBytes <= 0 {
+		res.conf.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	res.he = he
+	res.receivers = make(map[string]Receiver)
+	for name, h := range c.Hooks {
+		var rec Receiver
+		var err error
+		switch h.Type {
+		case "", "template":
+			rec, err = newTemplateReceiver(he, name, h)
+		case "gitea", "github":
+			rec, err = newGitReceiver(h)
+		default:
+			err = errors.New("unknown type " + h.Type + ", use template, gitea or github")
+		}
+		if err != nil {
+			return nil, errors.New("Error in hook " + name + ": " + err.Error())
+		}
+		res.receivers[name] = rec
+	}
+	return res, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, hookPath)
+	rec, ex := s.receivers[name]
+	if !ex {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.conf.MaxBodyBytes))
+	if err != nil {
+		http.Error(w, "Error reading body: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	status, err := rec.Receive(s.he, r, body)
+	if err != nil {
+		s.he.Logger.Warnw("Webhook failed", "Hook", name, "Status", status, "Error", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.he.Logger.Infow("Webhook received", "Hook", name, "Status", status)
+	w.WriteHeader(status)
+}
+
+// Serve runs the listener until ctx is cancelled, it does nothing if the
+// webhooks are disabled.
+func (s *Server) Serve(ctx context.Context) error {
+	if !s.conf.Enabled {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(hookPath, s)
+	server := &http.Server{Addr: s.conf.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(sctx)
+	}()
+	s.he.Logger.Infow("Serving webhooks", "listen", s.conf.Listen, "hooks", len(s.receivers))
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.New("Error serving webhooks: " + err.Error())
+	}
+	return nil
+}